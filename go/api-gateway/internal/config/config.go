@@ -1,6 +1,7 @@
 package config
 
 import (
+	"grpc_module/mtls"
 	"log"
 	"os"
 
@@ -16,6 +17,7 @@ type Config struct {
 	CartServiceURL         string
 	PaymentServiceURL      string
 	NotificationServiceURL string
+	TLS                    mtls.Config
 }
 
 func LoadConfig() *Config {
@@ -32,5 +34,6 @@ func LoadConfig() *Config {
 		CartServiceURL:         os.Getenv("CART_SERVICE_URL"),
 		PaymentServiceURL:      os.Getenv("PAYMENT_SERVICE_URL"),
 		NotificationServiceURL: os.Getenv("NOTIFICATION_SERVICE_URL"),
+		TLS:                    mtls.FromEnv(),
 	}
 }
@@ -9,10 +9,11 @@ import (
 	"grpc_module/auth/authpb"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 )
 
-func AuthMiddleware(jwtSecret string, _ any) func(http.Handler) http.Handler {
+// creds are the gateway's mtls client creds for auth-service
+func AuthMiddleware(jwtSecret string, creds credentials.TransportCredentials) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -24,7 +25,7 @@ func AuthMiddleware(jwtSecret string, _ any) func(http.Handler) http.Handler {
 			token := cookie.Value
 
 			// grpc connection with auth-service
-			conn, err := grpc.NewClient("localhost:42070", grpc.WithTransportCredentials(insecure.NewCredentials()))
+			conn, err := grpc.NewClient("localhost:42070", grpc.WithTransportCredentials(creds))
 			if err != nil {
 				log.Printf("failed to connect to auth service: %v", err)
 				http.Error(w, "authentication service unavailable", http.StatusServiceUnavailable)
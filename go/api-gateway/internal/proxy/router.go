@@ -4,6 +4,7 @@ import (
 	"api-gateway/internal/config"
 	"api-gateway/internal/middleware"
 	"context"
+	"grpc_module/mtls"
 	"io"
 	"log"
 	"net/http"
@@ -19,32 +20,66 @@ func NewRouter(cfg *config.Config) http.Handler {
 	r := &Router{cfg: cfg}
 	mux := http.NewServeMux()
 
-	authMW := middleware.AuthMiddleware(cfg.JWTSecret, nil)
+	authCreds, err := mtls.ClientCredentials(cfg.TLS, "auth-service")
+	if err != nil {
+		log.Fatalf("[Error]: loading mtls client config: %v", err)
+	}
+	authMW := middleware.AuthMiddleware(cfg.JWTSecret, authCreds)
 
 	// Public routes
 	mux.HandleFunc("/register", r.handleProxy(cfg.UserServiceURL))
 	mux.HandleFunc("/login", r.handleProxy(cfg.UserServiceURL))
+	// opened from the verification email, the token is the auth here
+	mux.HandleFunc("/profile/verify-email", r.handleProxy(cfg.UserServiceURL))
+	// unsubscribe links in mails, same deal
+	mux.HandleFunc("/unsubscribe", r.handleProxy(cfg.UserServiceURL))
 
 	// Products - public rn
 	mux.HandleFunc("/products/get", r.handleProxy(cfg.ProductServiceURL))
 	mux.HandleFunc("/products/search", r.handleProxy(cfg.ProductServiceURL))
+	mux.HandleFunc("GET /products/suggest", r.handleProxy(cfg.ProductServiceURL))
+	// reads are public, product-service checks the admin role on writes
+	mux.HandleFunc("/categories", r.handleProxy(cfg.ProductServiceURL))
+	mux.HandleFunc("/categories/", r.handleProxy(cfg.ProductServiceURL))
+	mux.HandleFunc("GET /products/{id}/reviews", r.handleProxy(cfg.ProductServiceURL))
+	mux.HandleFunc("GET /products/{id}/recommendations", r.handleProxy(cfg.ProductServiceURL))
 
 	// Protected routes
 	protectedRoutes := map[string]string{
-		"/profile":          cfg.UserServiceURL,
-		"/users/":           cfg.UserServiceURL,
-		"/orders/":          cfg.OrderServiceURL,
-		"/orders":           cfg.OrderServiceURL,
-		"/cart/getcart":     cfg.CartServiceURL,
-		"/cart/add":         cfg.CartServiceURL,
-		"/cart":             cfg.CartServiceURL,
-		"/cart/":            cfg.CartServiceURL,
-		"/payments/":        cfg.PaymentServiceURL,
-		"/payments/intent":  cfg.PaymentServiceURL,
-		"/payments/webhook": cfg.PaymentServiceURL,
-		"/payments":         cfg.PaymentServiceURL,
-		"/notifications/":   cfg.NotificationServiceURL,
-		"/notifications":    cfg.NotificationServiceURL,
+		"/profile":               cfg.UserServiceURL,
+		"/profile/":              cfg.UserServiceURL,
+		"/users/":                cfg.UserServiceURL,
+		"/admin/":                cfg.UserServiceURL,
+		"/orders/":               cfg.OrderServiceURL,
+		"/orders":                cfg.OrderServiceURL,
+		"/products/item":         cfg.ProductServiceURL,
+		"/products/stock":        cfg.ProductServiceURL,
+		"/products/stock/adjust": cfg.ProductServiceURL,
+		"/products/variants":     cfg.ProductServiceURL,
+		"/cart/getcart":          cfg.CartServiceURL,
+		"/cart/add":              cfg.CartServiceURL,
+		"/cart":                  cfg.CartServiceURL,
+		"/cart/":                 cfg.CartServiceURL,
+		"/payments/":             cfg.PaymentServiceURL,
+		"/payments/intent":       cfg.PaymentServiceURL,
+		"/payments/webhook":      cfg.PaymentServiceURL,
+		"/payments":              cfg.PaymentServiceURL,
+		"/notifications/":        cfg.NotificationServiceURL,
+		"/notifications":         cfg.NotificationServiceURL,
+
+		// writing reviews, product-service checks the admin role on moderation
+		"POST /products/{id}/reviews": cfg.ProductServiceURL,
+		"/products/{id}/reviews/mine": cfg.ProductServiceURL,
+		"/reviews":                    cfg.ProductServiceURL,
+		"/reviews/":                   cfg.ProductServiceURL,
+		// gallery edits, admin only
+		"/products/{id}/images":  cfg.ProductServiceURL,
+		"/products/{id}/images/": cfg.ProductServiceURL,
+		// bulk import and export, admin only
+		"/catalog/": cfg.ProductServiceURL,
+		// price history and schedules, admin only
+		"/products/{id}/prices":  cfg.ProductServiceURL,
+		"/products/{id}/prices/": cfg.ProductServiceURL,
 	}
 
 	for route, serviceURL := range protectedRoutes {
@@ -5,16 +5,19 @@ import (
 	"auth-service/internal/logger"
 	"auth-service/internal/service"
 	"auth-service/utils"
+	"context"
 	"fmt"
 	"grpc_module/auth/authpb"
+	"grpc_module/mtls"
+	"grpc_module/user/userpb"
 	"net"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -29,6 +32,7 @@ func main() {
 		return
 	}
 	logDev := os.Getenv("LOG_DEV")
+	userAddr := os.Getenv("USER_SERVICE_ADDR")
 	utils.InitJWT()
 
 	// init logger
@@ -50,9 +54,48 @@ func main() {
 		return
 	}
 	authHandler := handler.NewAuthHandler(logger, authService)
-	server := grpc.NewServer(
-		grpc.Creds(insecure.NewCredentials()),
-	)
+	tlsCfg := mtls.FromEnv()
+
+	// disabled users live in user-service, pull them in so their tokens keep failing after a restart
+	userCreds, err := mtls.ClientCredentials(tlsCfg, "user-service")
+	if err != nil {
+		logger.Error("failed to load mtls client config", zap.Error(err))
+		return
+	}
+	userConn, err := grpc.NewClient(userAddr, grpc.WithTransportCredentials(userCreds))
+	if err != nil {
+		logger.Error("failed to create user-service client", zap.Error(err))
+		return
+	}
+	defer userConn.Close()
+	go func() {
+		userClient := userpb.NewUserServiceClient(userConn)
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			n, err := authService.Revocations().LoadDisabled(ctx, userClient)
+			cancel()
+			if err == nil {
+				logger.Info("loaded disabled users", zap.Int("count", n))
+				return
+			}
+			logger.Warn("couldnt load disabled users, retrying", zap.Error(err))
+			time.Sleep(10 * time.Second)
+		}
+	}()
+
+	// only these services can talk to auth, identified by their client cert CN
+	allow := mtls.AllowList{
+		"/auth.AuthService/GeneratePassword": {"user-service"},
+		"/auth.AuthService/Authenticate":     {"user-service"},
+		"/auth.AuthService/ValidateToken":    {"api-gateway", "user-service", "product-service", "cart-service", "payment-service"},
+		"/auth.AuthService/RevokeUser":       {"user-service"},
+	}.WithReflection(mtls.DevClient)
+	serverOpts, err := mtls.ServerOptions(tlsCfg, allow)
+	if err != nil {
+		logger.Error("failed to load mtls config", zap.Error(err))
+		return
+	}
+	server := grpc.NewServer(serverOpts...)
 
 	authpb.RegisterAuthServiceServer(server, authHandler)
 	reflection.Register(server)
@@ -6,6 +6,8 @@ import (
 	"grpc_module/auth/authpb"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AuthHandler struct {
@@ -22,7 +24,7 @@ func NewAuthHandler(logger *zap.Logger, authService *service.AuthService) *AuthH
 }
 
 func (h *AuthHandler) Authenticate(ctx context.Context, req *authpb.AuthRequest) (*authpb.AuthResponse, error) {
-	token, err := h.authService.Authenticate(req.Email, req.Password, req.HashedPassword, req.UserId)
+	token, err := h.authService.Authenticate(req.Email, req.Password, req.HashedPassword, req.UserId, req.Role)
 	if err != nil {
 		h.logger.Error("err in authenticating user", zap.String("email", req.Email), zap.Error(err))
 		return &authpb.AuthResponse{
@@ -40,13 +42,23 @@ func (h *AuthHandler) Authenticate(ctx context.Context, req *authpb.AuthRequest)
 }
 
 func (h *AuthHandler) ValidateToken(ctx context.Context, req *authpb.ValidateTokenRequest) (*authpb.ValidateTokenResponse, error) {
-	valid, userid := h.authService.ValidateToken(req.Token)
+	valid, userid, role := h.authService.ValidateToken(req.Token)
 
 	return &authpb.ValidateTokenResponse{
 		Valid:  valid,
 		UserId: userid,
+		Role:   role,
 	}, nil
 }
+
+func (h *AuthHandler) RevokeUser(ctx context.Context, req *authpb.RevokeUserRequest) (*authpb.RevokeUserResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing user id")
+	}
+	h.authService.RevokeUser(req.UserId, req.Disabled)
+	h.logger.Info("user tokens revoked", zap.String("user_id", req.UserId), zap.Bool("disabled", req.Disabled))
+	return &authpb.RevokeUserResponse{}, nil
+}
 func (h *AuthHandler) GeneratePassword(ctx context.Context, req *authpb.BcryptPasswordRequest) (*authpb.BcryptPasswordResponse, error) {
 	hashed, err := h.authService.GeneratePassword(req.Password)
 	if err != nil || req.Password == "" {
@@ -8,24 +8,27 @@ import (
 )
 
 type AuthServiceInterface interface {
-	Authenticate(email, password, hashedPassword string) (token string, err error)
-	ValidateToken(token string) (valid bool, email string)
+	Authenticate(email, password, hashedPassword, userid, role string) (token string, err error)
+	ValidateToken(token string) (valid bool, userId, role string)
 	GeneratePassword(password string) (string, error)
+	RevokeUser(userId string, disabled bool)
 }
 
-type AuthService struct{}
+type AuthService struct {
+	revocations *Revocations
+}
 
 func NewAuthService() (*AuthService, error) {
-	return &AuthService{}, nil
+	return &AuthService{revocations: NewRevocations()}, nil
 }
 
-func (s *AuthService) Authenticate(email, password, hashedPassword string, userid string) (token string, err error) {
+func (s *AuthService) Authenticate(email, password, hashedPassword, userid, role string) (token string, err error) {
 	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	if err != nil {
 		return "", errors.New("invalid credentials")
 	}
 
-	token, err = utils.GenerateJWT(email, userid)
+	token, err = utils.GenerateJWT(email, userid, role)
 	if err != nil {
 		return "", err
 	}
@@ -33,12 +36,28 @@ func (s *AuthService) Authenticate(email, password, hashedPassword string, useri
 	return token, nil
 }
 
-func (s *AuthService) ValidateToken(token string) (valid bool, userId string) {
+func (s *AuthService) ValidateToken(token string) (valid bool, userId, role string) {
 	claims, err := utils.ValidateJWT(token)
 	if err != nil {
-		return false, ""
+		return false, "", ""
+	}
+	if claims.IssuedAt == nil || !s.revocations.Allowed(claims.UserID, claims.IssuedAt.Time) {
+		return false, "", ""
 	}
-	return true, claims.UserID
+	return true, claims.UserID, claims.Role
+}
+
+func (s *AuthService) RevokeUser(userId string, disabled bool) {
+	if disabled {
+		s.revocations.Disable(userId)
+		return
+	}
+	s.revocations.Enable(userId)
+}
+
+// Revocations exposes the list so main can seed it from user-service on startup.
+func (s *AuthService) Revocations() *Revocations {
+	return s.revocations
 }
 func (s *AuthService) GeneratePassword(password string) (string, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"grpc_module/user/userpb"
+	"sync"
+	"time"
+)
+
+// Revocations is the in-memory list of users whose tokens must be rejected.
+// Disabled users are re-read from user-service on startup, the issued-before
+// cutoffs are not, worst case an old token lives until it expires.
+type Revocations struct {
+	mu        sync.RWMutex
+	disabled  map[string]bool
+	notBefore map[string]time.Time
+}
+
+func NewRevocations() *Revocations {
+	return &Revocations{
+		disabled:  make(map[string]bool),
+		notBefore: make(map[string]time.Time),
+	}
+}
+
+func (r *Revocations) Disable(userId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.disabled[userId] = true
+}
+
+// Enable lifts a disable, tokens issued before now stay invalid.
+func (r *Revocations) Enable(userId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.disabled, userId)
+	// jwt iat has second precision
+	r.notBefore[userId] = time.Now().Truncate(time.Second)
+}
+
+func (r *Revocations) Allowed(userId string, issuedAt time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.disabled[userId] {
+		return false
+	}
+	cutoff, ok := r.notBefore[userId]
+	return !ok || !issuedAt.Before(cutoff)
+}
+
+// LoadDisabled pages through the disabled users in user-service.
+func (r *Revocations) LoadDisabled(ctx context.Context, client userpb.UserServiceClient) (int, error) {
+	const pageSize = 500
+	loaded := 0
+	for page := int32(1); ; page++ {
+		resp, err := client.ListUsers(ctx, &userpb.ListUsersRequest{
+			Status:   "disabled",
+			Page:     page,
+			PageSize: pageSize,
+		})
+		if err != nil {
+			return loaded, fmt.Errorf("listing disabled users: %w", err)
+		}
+		for _, u := range resp.Users {
+			r.Disable(u.Id)
+			loaded++
+		}
+		if len(resp.Users) < pageSize {
+			return loaded, nil
+		}
+	}
+}
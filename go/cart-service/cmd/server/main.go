@@ -3,23 +3,26 @@ package main
 import (
 	"cart-service/internal/database"
 	"cart-service/internal/handlers"
+	"cart-service/internal/kafka"
 	"cart-service/internal/logger"
+	"context"
 	"fmt"
 	"grpc_module/auth/authpb"
 	"grpc_module/cart/cartpb"
+	"grpc_module/mtls"
 	"grpc_module/product/productpb"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -34,8 +37,19 @@ func main() {
 	logDev := os.Getenv("LOG_DEV")
 	mongoUri := os.Getenv("MONGO_URI")
 	httpPort := os.Getenv("HTTP_PORT")
-	// kafkaBrokers := []string{os.Getenv("KAFKA_BROKERS")}
-	// kafkaTopic := os.Getenv("KAFKA_TOPIC")
+	brokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
+	productTopic := os.Getenv("PRODUCT_TOPIC")
+	if productTopic == "" {
+		productTopic = "product-service"
+	}
+	userTopic := os.Getenv("USER_EVENTS_TOPIC")
+	if userTopic == "" {
+		userTopic = "user-events"
+	}
+	privacyTopic := os.Getenv("PRIVACY_TOPIC")
+	if privacyTopic == "" {
+		privacyTopic = "privacy-events"
+	}
 
 	// init logger
 	logMode, err := strconv.ParseBool(logDev)
@@ -57,14 +71,23 @@ func main() {
 	}
 	repo := database.NewMongoRepo(mongoClient, dbname)
 
-	// grpc
-	authConn, err := grpc.NewClient("localhost:"+authgrpcport, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// grpc, mtls both ways
+	tlsCfg := mtls.FromEnv()
+	authCreds, err := mtls.ClientCredentials(tlsCfg, "auth-service")
+	if err != nil {
+		log.Fatalf("couldnt load mtls client config: %v", err)
+	}
+	productCreds, err := mtls.ClientCredentials(tlsCfg, "product-service")
+	if err != nil {
+		log.Fatalf("couldnt load mtls client config: %v", err)
+	}
+	authConn, err := grpc.NewClient("localhost:"+authgrpcport, grpc.WithTransportCredentials(authCreds))
 	if err != nil {
 		log.Fatalf("couldnt connect to authservice grpc: %v", err)
 	}
 	defer authConn.Close()
 
-	productConn, err := grpc.NewClient("localhost:"+productgrpcport, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	productConn, err := grpc.NewClient("localhost:"+productgrpcport, grpc.WithTransportCredentials(productCreds))
 	if err != nil {
 		log.Fatalf("couldnt connect to authservice grpc: %v", err)
 	}
@@ -72,10 +95,32 @@ func main() {
 	productClient := productpb.NewProductServiceClient(productConn)
 	authClient := authpb.NewAuthServiceClient(authConn)
 	carthandler := handlers.NewCartHandler(repo, logger, productClient, authClient)
-	// cartProducer := kafka.NewCartProducer(kafkaBrokers, kafkaTopic)
-	// cartConsumer := kafka.NewCartConsumer(kafkaBrokers, kafkaTopic, "cart-service-group", repo)
 
-	server := grpc.NewServer(grpc.Creds(insecure.NewCredentials()))
+	// product changes: prices, variants, stock, deletes
+	cartConsumer := kafka.NewCartConsumer(brokers, productTopic, "cart-service-group", repo)
+	defer cartConsumer.Close()
+	go func() {
+		if err := cartConsumer.Consume(context.Background()); err != nil {
+			log.Printf("cart consumer err: %v", err)
+		}
+	}()
+
+	// export / erasure requests from user-service
+	privacyConsumer := kafka.NewPrivacyConsumer(brokers, userTopic, privacyTopic, "cart-service-privacy-group", repo)
+	defer privacyConsumer.Close()
+	go func() {
+		if err := privacyConsumer.Consume(context.Background()); err != nil {
+			log.Printf("privacy consumer err: %v", err)
+		}
+	}()
+
+	// no internal callers of the cart rpcs yet, so only the dev cert
+	allow := mtls.AllowList{}.WithReflection(mtls.DevClient)
+	serverOpts, err := mtls.ServerOptions(tlsCfg, allow)
+	if err != nil {
+		log.Fatalf("couldnt load mtls server config: %v", err)
+	}
+	server := grpc.NewServer(serverOpts...)
 	cartpb.RegisterCartServiceServer(server, carthandler)
 	reflection.Register(server)
 
@@ -14,8 +14,11 @@ import (
 type CartRepository interface {
 	GetCart(ctx context.Context, userID string) (*models.Cart, error)
 	AddToCart(ctx context.Context, userID string, productData *models.CartItem) (*models.CartItem, error)
-	RemoveFromCart(ctx context.Context, userID string, productID string) error
+	RemoveFromCart(ctx context.Context, userID string, productID, variantID string) error
 	UpdateProductInCarts(ctx context.Context, item *models.CartItem) error
+	// RemoveProductFromCarts drops every line of the product, all variants, returns the carts changed
+	RemoveProductFromCarts(ctx context.Context, productID string) (int64, error)
+	DeleteCart(ctx context.Context, userID string) error
 }
 
 type mongoRepo struct {
@@ -50,8 +53,18 @@ func (repo *mongoRepo) GetCart(ctx context.Context, userID string) (*models.Cart
 	return &cart, nil
 }
 
+// lines without a variant have no variant_id, matching nil also matches missing
+func variantMatch(variantID string) any {
+	if variantID == "" {
+		return nil
+	}
+	return variantID
+}
+
+// UpdateProductInCarts refreshes the denormalized fields of the lines for
+// item.ProductID and item.VariantID.
 func (repo *mongoRepo) UpdateProductInCarts(ctx context.Context, item *models.CartItem) error {
-	filter := bson.M{"items.product_id": item.ProductID}
+	filter := bson.M{"items": bson.M{"$elemMatch": bson.M{"product_id": item.ProductID, "variant_id": variantMatch(item.VariantID)}}}
 	update := bson.M{
 		"$set": bson.M{
 			"items.$[elem].name":        item.Name,
@@ -60,13 +73,33 @@ func (repo *mongoRepo) UpdateProductInCarts(ctx context.Context, item *models.Ca
 			"updated_at":                time.Now(),
 		},
 	}
-	opts := options.UpdateMany().SetArrayFilters([]interface{}{bson.M{"elem.product_id": item.ProductID}}).SetUpsert(false)
+	opts := options.UpdateMany().SetArrayFilters([]interface{}{bson.M{
+		"elem.product_id": item.ProductID,
+		"elem.variant_id": variantMatch(item.VariantID),
+	}}).SetUpsert(false)
 	_, err := repo.col.UpdateMany(ctx, filter, update, opts)
 	return err
 }
 
+func (repo *mongoRepo) RemoveProductFromCarts(ctx context.Context, productID string) (int64, error) {
+	res, err := repo.col.UpdateMany(ctx,
+		bson.M{"items.product_id": productID},
+		bson.M{
+			"$pull": bson.M{"items": bson.M{"product_id": productID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
 func (repo *mongoRepo) AddToCart(ctx context.Context, userID string, item *models.CartItem) (*models.CartItem, error) {
-	filter := bson.M{"user_id": userID, "items.product_id": item.ProductID}
+	filter := bson.M{
+		"user_id": userID,
+		"items":   bson.M{"$elemMatch": bson.M{"product_id": item.ProductID, "variant_id": variantMatch(item.VariantID)}},
+	}
 	update := bson.M{
 		"$inc": bson.M{"items.$.quantity": item.Quantity},
 		"$set": bson.M{"updated_at": time.Now()},
@@ -94,10 +127,10 @@ func (repo *mongoRepo) AddToCart(ctx context.Context, userID string, item *model
 	return item, nil
 }
 
-func (repo *mongoRepo) RemoveFromCart(ctx context.Context, userID string, productID string) error {
+func (repo *mongoRepo) RemoveFromCart(ctx context.Context, userID string, productID, variantID string) error {
 	update := bson.M{
 		"$pull": bson.M{
-			"items": bson.M{"product_id": productID},
+			"items": bson.M{"product_id": productID, "variant_id": variantMatch(variantID)},
 		},
 		"$set": bson.M{
 			"updated_at": time.Now(),
@@ -115,3 +148,8 @@ func (repo *mongoRepo) RemoveFromCart(ctx context.Context, userID string, produc
 
 	return nil
 }
+
+func (repo *mongoRepo) DeleteCart(ctx context.Context, userID string) error {
+	_, err := repo.col.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -113,6 +113,7 @@ func (h *CartHandler) AddToCartHTTP(w http.ResponseWriter, r *http.Request) {
 	userID := authResp.UserId
 	type AddToCartRequest struct {
 		ProductID string `json:"product_id"`
+		VariantID string `json:"variant_id"`
 	}
 
 	var req AddToCartRequest
@@ -135,14 +136,65 @@ func (h *CartHandler) AddToCartHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	product := productResp.Product
+	if product.DeletedAt != "" {
+		http.Error(w, "product is no longer available", http.StatusGone)
+		return
+	}
 	item := &models.CartItem{
 		ProductID:  productID,
-		Name:       productResp.Product.Name,
-		PriceCents: productResp.Product.Pricecents,
-		Image:      productResp.Product.Image,
+		Name:       product.Name,
+		PriceCents: product.Pricecents,
+		Image:      product.Image,
 		Quantity:   1,
 		AddedAt:    time.Now(),
 	}
+	available := product.StockAvailable
+	if len(product.Variants) > 0 || req.VariantID != "" {
+		var variant *productpb.Variant
+		for _, v := range product.Variants {
+			if v.Id == req.VariantID {
+				variant = v
+				break
+			}
+		}
+		if variant == nil {
+			http.Error(w, "pick one of the product's variants", http.StatusBadRequest)
+			return
+		}
+		item.VariantID = variant.Id
+		item.SKU = variant.Sku
+		item.Options = variant.Options
+		item.PriceCents = variant.Pricecents
+		if variant.Image != "" {
+			item.Image = variant.Image
+		}
+		available = variant.StockAvailable
+	}
+
+	// the line may already be in the cart, the new total has to fit the stock
+	cart, err := h.repo.GetCart(ctx, userID)
+	if err != nil {
+		h.logger.Error("err fetching cart items", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	quantity := item.Quantity
+	for _, existing := range cart.Items {
+		if existing.ProductID == productID && existing.VariantID == item.VariantID {
+			quantity += existing.Quantity
+		}
+	}
+	if quantity > available {
+		h.logger.Info("not enough stock to add to cart",
+			zap.String("product_id", productID),
+			zap.String("variant_id", item.VariantID),
+			zap.Int64("wanted", quantity),
+			zap.Int64("available", available),
+		)
+		http.Error(w, "not enough stock", http.StatusConflict)
+		return
+	}
 
 	addedItem, err := h.repo.AddToCart(context.Background(), userID, item)
 	if err != nil {
@@ -189,6 +241,7 @@ func (h *CartHandler) RemoveFromCartHTTP(w http.ResponseWriter, r *http.Request)
 
 	type RemoveFromCartRequest struct {
 		ProductID string `json:"product_id"`
+		VariantID string `json:"variant_id"`
 	}
 	var req RemoveFromCartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -197,7 +250,7 @@ func (h *CartHandler) RemoveFromCartHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.repo.RemoveFromCart(context.Background(), userID, req.ProductID)
+	err = h.repo.RemoveFromCart(context.Background(), userID, req.ProductID, req.VariantID)
 	if err != nil {
 		h.logger.Error("err removing from cart", zap.String("path", r.URL.Path), zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -216,6 +269,7 @@ func (h *CartHandler) RemoveFromCartHTTP(w http.ResponseWriter, r *http.Request)
 func (h *CartHandler) AddToCart(ctx context.Context, req *cartpb.AddToCartRequest) (*cartpb.AddToCartResponse, error) {
 	item := &models.CartItem{
 		ProductID:  req.Item.ProductId,
+		VariantID:  req.Item.VariantId,
 		Name:       req.Item.Name,
 		Image:      req.Item.Image,
 		PriceCents: req.Item.PriceCents,
@@ -232,6 +286,7 @@ func (h *CartHandler) AddToCart(ctx context.Context, req *cartpb.AddToCartReques
 	resp := &cartpb.AddToCartResponse{
 		Item: &cartpb.CartItem{
 			ProductId:  addedItem.ProductID,
+			VariantId:  addedItem.VariantID,
 			Name:       addedItem.Name,
 			Image:      addedItem.Image,
 			PriceCents: addedItem.PriceCents,
@@ -243,7 +298,7 @@ func (h *CartHandler) AddToCart(ctx context.Context, req *cartpb.AddToCartReques
 }
 
 func (h *CartHandler) RemoveFromCart(ctx context.Context, req *cartpb.RemoveFromCartRequest) (*cartpb.RemoveFromCartResponse, error) {
-	err := h.repo.RemoveFromCart(ctx, req.UserId, req.ProductId)
+	err := h.repo.RemoveFromCart(ctx, req.UserId, req.ProductId, req.VariantId)
 	if err != nil {
 		log.Printf("Error remove from cart: %v", err)
 		return &cartpb.RemoveFromCartResponse{
@@ -269,6 +324,7 @@ func (h *CartHandler) GetCart(ctx context.Context, req *cartpb.GetCartRequest) (
 	for _, i := range cart.Items {
 		items = append(items, &cartpb.CartItem{
 			ProductId:  i.ProductID,
+			VariantId:  i.VariantID,
 			Name:       i.Name,
 			Image:      i.Image,
 			PriceCents: i.PriceCents,
@@ -66,22 +66,35 @@ func (c *CartConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) er
 
 	log.Printf("[CartConsumer] Received event type: %s", eventType)
 
-	if eventType != "Product Updated" {
+	switch eventType {
+	case "Product Updated":
+		var event ProductUpdatedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.handleProductUpdated(ctx, event)
+	case "product deleted":
+		var event ProductDeletedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.handleProductDeleted(ctx, event)
+	default:
 		log.Printf("Ignore event type: %s", eventType)
 		return nil
 	}
-
-	var event ProductUpdatedEvent
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
-		return err
-	}
-
-	return c.handleProductUpdated(ctx, event)
 }
 
 type ProductUpdatedEvent struct {
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Image      string                `json:"image"`
+	PriceCents int64                 `json:"price_cents"`
+	Variants   []VariantUpdatedEvent `json:"variants"`
+}
+
+type VariantUpdatedEvent struct {
 	ID         string `json:"id"`
-	Name       string `json:"name"`
 	Image      string `json:"image"`
 	PriceCents int64  `json:"price_cents"`
 }
@@ -103,10 +116,41 @@ func (c *CartConsumer) handleProductUpdated(ctx context.Context, event ProductUp
 		return err
 	}
 
+	// variant lines carry their own price and image
+	for _, v := range event.Variants {
+		variantUpdate := update
+		variantUpdate.VariantID = v.ID
+		variantUpdate.PriceCents = v.PriceCents
+		if v.Image != "" {
+			variantUpdate.Image = v.Image
+		}
+		if err := c.cartRepo.UpdateProductInCarts(ctx, &variantUpdate); err != nil {
+			log.Printf("Failed to update carts for product %s variant %s: %v", event.ID, v.ID, err)
+			return err
+		}
+	}
+
 	log.Printf("Successfully updated product %s in all relevant carts", event.ID)
 	return nil
 }
 
+type ProductDeletedEvent struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// handleProductDeleted takes the product out of every cart, it can't be
+// bought anymore.
+func (c *CartConsumer) handleProductDeleted(ctx context.Context, event ProductDeletedEvent) error {
+	n, err := c.cartRepo.RemoveProductFromCarts(ctx, event.ID)
+	if err != nil {
+		log.Printf("Failed to remove deleted product %s from carts: %v", event.ID, err)
+		return err
+	}
+	log.Printf("Removed deleted product %s from %d carts", event.ID, n)
+	return nil
+}
+
 func (c *CartConsumer) Close() error {
 	log.Println("Close Kafka reader")
 	return c.reader.Close()
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"cart-service/internal/database"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const serviceName = "cart-service"
+
+type UserExportRequestedEvent struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+}
+
+type UserDeletionRequestedEvent struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+}
+
+type UserDataExportedEvent struct {
+	JobID   string          `json:"job_id"`
+	UserID  string          `json:"user_id"`
+	Service string          `json:"service"`
+	Data    json.RawMessage `json:"data"`
+	Time    time.Time       `json:"time"`
+}
+
+type UserDataErasedEvent struct {
+	JobID   string    `json:"job_id"`
+	UserID  string    `json:"user_id"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+}
+
+// PrivacyConsumer answers user-service export and erasure jobs with the carts
+// we hold for that user.
+type PrivacyConsumer struct {
+	reader   *kafka.Reader
+	writer   *kafka.Writer
+	cartRepo database.CartRepository
+}
+
+func NewPrivacyConsumer(brokers []string, userTopic, privacyTopic, groupID string, repo database.CartRepository) *PrivacyConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   userTopic,
+		GroupID: groupID,
+	})
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        privacyTopic,
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	return &PrivacyConsumer{
+		reader:   reader,
+		writer:   writer,
+		cartRepo: repo,
+	}
+}
+
+func (c *PrivacyConsumer) Consume(ctx context.Context) error {
+	log.Println("PrivacyConsumer started ...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("PrivacyConsumer graceful shutdown")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetching message:", err)
+				continue
+			}
+
+			if err := c.ProcessMessage(ctx, msg); err != nil {
+				log.Println("Error processing message:", err)
+			} else {
+				if err := c.reader.CommitMessages(ctx, msg); err != nil {
+					log.Println("Couldn't commit message:", err)
+				}
+			}
+		}
+	}
+}
+
+func (c *PrivacyConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) error {
+	eventType := ""
+	for _, h := range msg.Headers {
+		if strings.ToLower(h.Key) == "event" {
+			eventType = string(h.Value)
+			break
+		}
+	}
+
+	switch eventType {
+	case "UserExportRequested":
+		var event UserExportRequestedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.handleExport(ctx, event)
+	case "UserDeletionRequested":
+		var event UserDeletionRequestedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.handleDeletion(ctx, event)
+	default:
+		return nil
+	}
+}
+
+func (c *PrivacyConsumer) handleExport(ctx context.Context, event UserExportRequestedEvent) error {
+	cart, err := c.cartRepo.GetCart(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cart)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exporting cart for job %s", event.JobID)
+	return c.publish(ctx, event.UserID, "UserDataExported", UserDataExportedEvent{
+		JobID:   event.JobID,
+		UserID:  event.UserID,
+		Service: serviceName,
+		Data:    data,
+		Time:    time.Now(),
+	})
+}
+
+func (c *PrivacyConsumer) handleDeletion(ctx context.Context, event UserDeletionRequestedEvent) error {
+	// carts are only useful to the user, nothing to keep
+	if err := c.cartRepo.DeleteCart(ctx, event.UserID); err != nil {
+		return err
+	}
+
+	log.Printf("Deleted cart for job %s", event.JobID)
+	return c.publish(ctx, event.UserID, "UserDataErased", UserDataErasedEvent{
+		JobID:   event.JobID,
+		UserID:  event.UserID,
+		Service: serviceName,
+		Time:    time.Now(),
+	})
+}
+
+func (c *PrivacyConsumer) publish(ctx context.Context, key, eventName string, event any) error {
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(key),
+		Value: bytes,
+		Headers: []kafka.Header{
+			{Key: "event", Value: []byte(eventName)},
+		},
+		Time: time.Now(),
+	})
+}
+
+func (c *PrivacyConsumer) Close() error {
+	log.Println("Close privacy consumer")
+	if err := c.reader.Close(); err != nil {
+		return err
+	}
+	return c.writer.Close()
+}
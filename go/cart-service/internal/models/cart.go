@@ -6,13 +6,18 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// CartItem lines are keyed by product and variant, the same product in two
+// sizes is two lines.
 type CartItem struct {
-	ProductID  string    `bson:"product_id" json:"product_id"`
-	Name       string    `bson:"name" json:"name"`
-	Image      string    `bson:"image" json:"image"`
-	PriceCents int64     `bson:"price_cents" json:"price_cents"`
-	Quantity   int64     `bson:"quantity" json:"quantity"`
-	AddedAt    time.Time `bson:"added_at" json:"added_at"`
+	ProductID  string            `bson:"product_id" json:"product_id"`
+	VariantID  string            `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	SKU        string            `bson:"sku,omitempty" json:"sku,omitempty"`
+	Options    map[string]string `bson:"options,omitempty" json:"options,omitempty"`
+	Name       string            `bson:"name" json:"name"`
+	Image      string            `bson:"image" json:"image"`
+	PriceCents int64             `bson:"price_cents" json:"price_cents"`
+	Quantity   int64             `bson:"quantity" json:"quantity"`
+	AddedAt    time.Time         `bson:"added_at" json:"added_at"`
 }
 
 type Cart struct {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"grpc_module/mtls"
+	"grpc_module/user/userpb"
 	"log"
 	"notification-service/kafka"
 	"notification-service/service"
@@ -11,6 +13,7 @@ import (
 	"syscall"
 
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -24,9 +27,28 @@ func main() {
 	brokers := strings.Split(brokerENV, ",")
 	kafkaTopic := os.Getenv("KAFKA_TOPIC")
 	kafkaTopis := strings.Split(kafkaTopic, ",")
+	// email change links come on their own topic, kept off the shared user events
+	verificationTopic := os.Getenv("VERIFICATION_TOPIC")
+	if verificationTopic == "" {
+		verificationTopic = "user-verification"
+	}
+	kafkaTopis = append(kafkaTopis, verificationTopic)
+	appURL := os.Getenv("APP_URL")
+	userAddr := os.Getenv("USER_SERVICE_ADDR")
+
+	// user-service is used to find the recipient when an event only has the user id
+	userCreds, err := mtls.ClientCredentials(mtls.FromEnv(), "user-service")
+	if err != nil {
+		log.Fatalf("[Error]: Failed to load mtls client config: %v", err)
+	}
+	userConn, err := grpc.NewClient(userAddr, grpc.WithTransportCredentials(userCreds))
+	if err != nil {
+		log.Fatalf("[Error]: Failed to connect to UserService: %v", err)
+	}
+	defer userConn.Close()
 
 	mailer := service.NewMailGunMailer(mailGunKey, mainGunDomain)
-	notificationConsumer := kafka.NewNotificationConsumer(brokers, kafkaTopis, "mail-service-group", mailer)
+	notificationConsumer := kafka.NewNotificationConsumer(brokers, kafkaTopis, "mail-service-group", mailer, appURL, userpb.NewUserServiceClient(userConn))
 
 	ctx, cancel := context.WithCancel(context.Background())
 
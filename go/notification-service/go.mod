@@ -5,6 +5,7 @@ go 1.25.1
 require (
 	github.com/joho/godotenv v1.5.1
 	github.com/segmentio/kafka-go v0.4.49
+	google.golang.org/grpc v1.76.0
 )
 
 require (
@@ -4,20 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"grpc_module/user/userpb"
 	"log"
+	"net/url"
 	model "notification-service/models"
 	"notification-service/service"
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type NotificationConsumer struct {
 	readers     []*kafka.Reader
 	emailSender service.Notifier
+	appURL      string // base url used in links inside mails
+	userClient  userpb.UserServiceClient
 }
 
-func NewNotificationConsumer(brokers []string, topics []string, groupID string, emailSender service.Notifier) *NotificationConsumer {
+func NewNotificationConsumer(brokers []string, topics []string, groupID string, emailSender service.Notifier, appURL string, userClient userpb.UserServiceClient) *NotificationConsumer {
 	var readers []*kafka.Reader
 	for _, topic := range topics {
 
@@ -31,6 +37,8 @@ func NewNotificationConsumer(brokers []string, topics []string, groupID string,
 	return &NotificationConsumer{
 		readers:     readers,
 		emailSender: emailSender,
+		appURL:      appURL,
+		userClient:  userClient,
 	}
 }
 
@@ -76,9 +84,24 @@ func (n *NotificationConsumer) ProcessMessage(ctx context.Context, msg kafka.Mes
 
 	log.Println("PRocessing event... ", eventType)
 
+	if optOutEvents[eventType] {
+		var send bool
+		var err error
+		ctx, send, err = n.applyPreferences(ctx, eventType, msg.Value)
+		if err != nil {
+			return err
+		}
+		if !send {
+			log.Println("User opted out of", eventType)
+			return nil
+		}
+	}
+
 	switch eventType {
 	case "UserCreated":
 		return n.handleUserCreated(ctx, msg.Value)
+	case "EmailVerificationRequested":
+		return n.handleEmailVerificationRequested(ctx, msg.Value)
 	case "OrderCreated":
 		return n.handleOrderCreated(ctx, msg.Value)
 	case "OrderShipped":
@@ -105,15 +128,106 @@ type OrderCreatedEvent struct {
 
 type OrderShippedEvent struct {
 	OrderID   string `json:"order_id"`
+	UserID    string `json:"user_id"`
 	UserEmail string `json:"user_email"`
 }
 
+// events a user can switch off in their preferences, the rest always go out
+var optOutEvents = map[string]bool{
+	"OrderCreated":    true,
+	"OrderShipped":    true,
+	"PaymentCaptured": true,
+	"PaymentFailed":   true,
+}
+
+type unsubscribeLinkKey struct{}
+
+// applyPreferences looks up the user's preferences for eventType. When the mail
+// should go out the returned ctx carries the unsubscribe link for send.
+func (n *NotificationConsumer) applyPreferences(ctx context.Context, eventType string, data []byte) (context.Context, bool, error) {
+	var ref struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return ctx, false, err
+	}
+	if ref.UserID == "" {
+		// older producers, nothing to check against
+		return ctx, true, nil
+	}
+
+	resp, err := n.getPreferences(ctx, ref.UserID)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound:
+			// account is gone
+			return ctx, false, nil
+		case codes.InvalidArgument:
+			log.Printf("not sending %s, user-service rejected user id %q: %v", eventType, ref.UserID, err)
+			return ctx, false, nil
+		}
+		// fail closed, an opt-out we couldn't read must not be ignored
+		return ctx, false, fmt.Errorf("fetching preferences of %s: %w", ref.UserID, err)
+	}
+
+	prefs := resp.Preferences
+	if toggles, ok := prefs.Channels[eventType]; ok && !toggles.Email {
+		return ctx, false, nil
+	}
+	if prefs.UnsubscribeToken == "" {
+		// not backfilled yet, send without the footer
+		return ctx, true, nil
+	}
+	link := fmt.Sprintf("%s/unsubscribe?token=%s&type=%s", n.appURL, url.QueryEscape(prefs.UnsubscribeToken), url.QueryEscape(eventType))
+	return context.WithValue(ctx, unsubscribeLinkKey{}, link), true, nil
+}
+
+// getPreferences retries errors that may go away, the consumer moves past a
+// message that fails so this is the only retry it gets.
+func (n *NotificationConsumer) getPreferences(ctx context.Context, userID string) (*userpb.GetPreferencesResponse, error) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := n.userClient.GetPreferences(ctx, &userpb.GetPreferencesRequest{UserId: userID})
+		if err == nil || attempt == preferencesAttempts {
+			return resp, err
+		}
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted, codes.Aborted:
+		default:
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+const preferencesAttempts = 4
+
+// send adds the unsubscribe footer and one-click headers when the mail has a link.
+func (n *NotificationConsumer) send(ctx context.Context, req service.EmailRequest) error {
+	if link, ok := ctx.Value(unsubscribeLinkKey{}).(string); ok {
+		req.Body += fmt.Sprintf(`
+		<p style="font-family: Monospace; font-size: 12px; color: #888;">Don't want these mails? <a href="%s">Unsubscribe</a></p>
+		`, link)
+		req.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + link + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	return n.emailSender.SendEmail(ctx, req)
+}
+
 type PaymentCapturedEvent struct {
 	PaymentID  string    `json:"payment_id"`
 	OrderID    string    `json:"order_id"`
 	Amount     int64     `json:"amount"`
 	Currency   string    `json:"currency"`
 	CapturedAt time.Time `json:"captured_at"`
+	UserID     string    `json:"user_id"`
 	UserEmail  string    `json:"user_email"`
 }
 
@@ -122,9 +236,30 @@ type PaymentFailedEvent struct {
 	OrderID   string    `json:"order_id"`
 	Reason    string    `json:"reason"`
 	FailedAt  time.Time `json:"failed_at"`
+	UserID    string    `json:"user_id"`
 	UserEmail string    `json:"user_email"`
 }
 
+// recipient returns email as is, or looks the user up when the event only carries an id.
+// Empty string with nil error means there's nobody to mail and the message can be dropped.
+func (n *NotificationConsumer) recipient(ctx context.Context, email, userID string) (string, error) {
+	if email != "" {
+		return email, nil
+	}
+	if userID == "" {
+		return "", nil
+	}
+	resp, err := n.userClient.GetUser(ctx, &userpb.GetUserRequest{UserId: userID})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound, codes.InvalidArgument:
+			return "", nil
+		}
+		return "", fmt.Errorf("resolving user %s: %w", userID, err)
+	}
+	return resp.User.Email, nil
+}
+
 func (n *NotificationConsumer) handleUserCreated(ctx context.Context, data []byte) error {
 	var event model.UserCreatedEvent
 	if err := json.Unmarshal(data, &event); err != nil {
@@ -145,9 +280,43 @@ func (n *NotificationConsumer) handleUserCreated(ctx context.Context, data []byt
 		`, event.Name),
 		Tags: []string{"user created", event.ID},
 	}
-	err := n.emailSender.SendEmail(ctx, emailReq)
+	err := n.send(ctx, emailReq)
 	return err
 }
+
+// handleEmailVerificationRequested mails the verify link to the new address of
+// an email change.
+func (n *NotificationConsumer) handleEmailVerificationRequested(ctx context.Context, data []byte) error {
+	var event model.EmailVerificationRequestedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	if event.Token == "" || event.Email == "" {
+		return nil
+	}
+	// don't mail a link that no longer works, the consumer may be far behind
+	if !event.ExpiresAt.IsZero() && time.Now().After(event.ExpiresAt) {
+		log.Println("Skipping expired email verification", event.ID)
+		return nil
+	}
+	link := fmt.Sprintf("%s/profile/verify-email?token=%s", n.appURL, url.QueryEscape(event.Token))
+	log.Println("Sending email verification mail", event.ID)
+	emailReq := service.EmailRequest{
+		To:      event.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(`
+		<div style="margin: 0 auto; font-family: Monospace; max-width: 600px;">
+  <h2 style="color: #de64deff;">Hi %s!</h2>
+  <p>Click the link below to start using this email address for your account.</p>
+  <p><a href="%s">%s</a></p>
+  <p>If you didn't ask for this you can ignore this mail, your current email stays as it is.</p>
+</div>
+		`, event.Name, link, link),
+		Tags: []string{"email-verification", event.ID},
+	}
+	return n.send(ctx, emailReq)
+}
+
 func (n *NotificationConsumer) handleOrderCreated(ctx context.Context, data []byte) error {
 	var event OrderCreatedEvent
 	if err := json.Unmarshal(data, &event); err != nil {
@@ -173,7 +342,7 @@ func (n *NotificationConsumer) handleOrderCreated(ctx context.Context, data []by
 		Tags: []string{"order-confirmation", event.OrderID},
 	}
 
-	err := n.emailSender.SendEmail(ctx, emailReq)
+	err := n.send(ctx, emailReq)
 	return err
 }
 
@@ -200,7 +369,7 @@ func (n *NotificationConsumer) handleOrderShipped(ctx context.Context, data []by
 		Tags: []string{"order-shipped", event.OrderID},
 	}
 
-	err := n.emailSender.SendEmail(ctx, emailReq)
+	err := n.send(ctx, emailReq)
 	return err
 }
 
@@ -209,8 +378,16 @@ func (n *NotificationConsumer) handlePaymentCaptured(ctx context.Context, data [
 	if err := json.Unmarshal(data, &event); err != nil {
 		return err
 	}
+	to, err := n.recipient(ctx, event.UserEmail, event.UserID)
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		log.Println("No recipient for payment captured", event.PaymentID)
+		return nil
+	}
 	emailReq := service.EmailRequest{
-		To:      event.UserEmail,
+		To:      to,
 		Subject: "Your payment is captured",
 		Body: fmt.Sprintf(`
 		<div style="font-family: Monospace; max-width: 600px; margin: 0 auto;">
@@ -222,10 +399,10 @@ func (n *NotificationConsumer) handlePaymentCaptured(ctx context.Context, data [
 			</div>
 			payment captured
 		`, event.PaymentID),
-		Tags: []string("payment-captured", event.PaymentID),
+		Tags: []string{"payment-captured", event.PaymentID},
 	}
 	log.Println("Payment captured", event.OrderID)
-	err := n.emailSender.SendEmail(ctx, emailReq)
+	err = n.send(ctx, emailReq)
 	return err
 }
 
@@ -234,8 +411,16 @@ func (n *NotificationConsumer) handlePaymentFailed(ctx context.Context, data []b
 	if err := json.Unmarshal(data, &event); err != nil {
 		return err
 	}
+	to, err := n.recipient(ctx, event.UserEmail, event.UserID)
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		log.Println("No recipient for payment failed", event.PaymentID)
+		return nil
+	}
 	emailReq := service.EmailRequest{
-		To:      event.UserEmail,
+		To:      to,
 		Subject: "Your payment is failed",
 		Body: fmt.Sprintf(`
 		<div style="font-family: Monospace; max-width: 600px; margin: 0 auto;">
@@ -247,10 +432,10 @@ func (n *NotificationConsumer) handlePaymentFailed(ctx context.Context, data []b
 			</div>
 			payment failed
 		`, event.PaymentID),
-		Tags: []string("payment-failed", event.PaymentID),
+		Tags: []string{"payment-failed", event.PaymentID},
 	}
 	log.Println("Payment failed", event.OrderID, event.Reason)
-	err := n.emailSender.SendEmail(ctx, emailReq)
+	err = n.send(ctx, emailReq)
 	return err
 }
 
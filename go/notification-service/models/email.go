@@ -8,6 +8,14 @@ type UserCreatedEvent struct {
 	Name  string    `json:"name"`
 	Time  time.Time `json:"time"`
 }
+type EmailVerificationRequestedEvent struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Time      time.Time `json:"time"`
+}
 type EmailStatus struct {
 	Email     string    `json:"email"`
 	Status    string    `json:"status"`
@@ -19,6 +19,8 @@ type EmailRequest struct {
 	Subject string   `json:"subject"`
 	Body    string   `json:"body"`
 	Tags    []string `json:"tags"`
+	// extra mail headers, e.g. List-Unsubscribe
+	Headers map[string]string `json:"headers,omitempty"`
 }
 type Notifier interface {
 	SendEmail(ctx context.Context, req EmailRequest) error
@@ -39,6 +41,9 @@ func (m *MailGunMailer) SendEmail(ctx context.Context, req EmailRequest) error {
 	form.Set("to", req.To)
 	form.Set("subject", req.Subject)
 	form.Set("text", req.Body)
+	for k, v := range req.Headers {
+		form.Set("h:"+k, v)
+	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
 		fmt.Sprintf("https://api.mailgun.net/v3/%s/messages", m.domain),
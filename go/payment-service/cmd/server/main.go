@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"grpc_module/auth/authpb"
+	"grpc_module/mtls"
 	"log"
 	"net/http"
 	"os"
@@ -15,7 +16,6 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"payment-service/internal/database"
 	"payment-service/internal/handlers"
@@ -36,6 +36,14 @@ func main() {
 	kafkaBrokers := []string{os.Getenv("KAFKA_BROKERS")}
 	kafkaTopic := os.Getenv("KAFKA_TOPIC")
 	authGrpcServicePort := os.Getenv("GRPC_Auth_Service_PORT")
+	userTopic := os.Getenv("USER_EVENTS_TOPIC")
+	if userTopic == "" {
+		userTopic = "user-events"
+	}
+	privacyTopic := os.Getenv("PRIVACY_TOPIC")
+	if privacyTopic == "" {
+		privacyTopic = "privacy-events"
+	}
 	if stripeKey == "" || mongoURI == "" || kafkaTopic == "" || authGrpcServicePort == "" || paymentHttpPort == "" {
 		log.Fatal("missing required env vars")
 	}
@@ -52,7 +60,11 @@ func main() {
 
 	repo := database.NewMongoPaymentRepo(mongoClient, dbname)
 	// init services
-	authConn, err := grpc.NewClient(authGrpcServicePort, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	authCreds, err := mtls.ClientCredentials(mtls.FromEnv(), "auth-service")
+	if err != nil {
+		log.Fatal("couldnt load mtls client config: ", err)
+	}
+	authConn, err := grpc.NewClient(authGrpcServicePort, grpc.WithTransportCredentials(authCreds))
 	if err != nil {
 		log.Fatal("couldnt connect to authservice: ", err)
 
@@ -61,6 +73,8 @@ func main() {
 	authClient := authpb.NewAuthServiceClient(authConn)
 	paymentProducer := kafka.NewPaymentProducer(kafkaBrokers, kafkaTopic)
 	// paymentConsumer := kafka.NewPaymentConsumer(kafkaBrokers, kafkaTopic, "payment-service-group", repo)
+	privacyConsumer := kafka.NewPrivacyConsumer(kafkaBrokers, userTopic, privacyTopic, "payment-service-privacy-group", repo)
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
 
 	stripeProvider := service.NewStripeProvider(stripeKey, stripeWebhook, kafka.NewPaymentProducer(kafkaBrokers, "payment"))
 
@@ -76,6 +90,11 @@ func main() {
 		}
 	}()
 
+	go func() {
+		if err := privacyConsumer.Consume(consumerCtx); err != nil {
+			log.Println("privacy consumer error:", err)
+		}
+	}()
 	// go func() {
 	// 	log.Println("Kafka consumer starting")
 	// 	if err := consumer.Consume(context.Background()); err != nil {
@@ -87,6 +106,11 @@ func main() {
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 		log.Println("[Shutting down]: Payment service gracefully")
+		log.Println("[Shutting down]: PrivacyConsumer")
+		stopConsumer()
+		if err := privacyConsumer.Close(); err != nil {
+			log.Printf("[Error]: closing PrivacyConsumer: %v", err)
+		}
 		log.Println("[Shutting down]: PaymentProducer")
 		if err := paymentProducer.Close(ctx); err != nil {
 			log.Printf("[Error]: closing PaymentProducer: %v", err)
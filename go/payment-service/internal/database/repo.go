@@ -15,6 +15,8 @@ type PaymentRepository interface {
 	CreatePayment(ctx context.Context, p *models.Payment) error
 	GetPaymentByOrderID(ctx context.Context, orderID string) (*models.Payment, error)
 	UpdatePaymentStatus(ctx context.Context, orderID string, status models.PaymentStatus, providerRef, failReason string) error
+	GetPaymentsByUserID(ctx context.Context, userID string) ([]*models.Payment, error)
+	AnonymizeUser(ctx context.Context, userID, tombstone string) error
 }
 
 type mongoPaymentRepo struct {
@@ -65,3 +67,39 @@ func (m *mongoPaymentRepo) UpdatePaymentStatus(ctx context.Context, orderID stri
 	}
 	return nil
 }
+
+func (m *mongoPaymentRepo) GetPaymentsByUserID(ctx context.Context, userID string) ([]*models.Payment, error) {
+	payments := []*models.Payment{}
+	res, err := m.col.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+
+	for res.Next(ctx) {
+		var payment models.Payment
+		if err := res.Decode(&payment); err != nil {
+			return nil, err
+		}
+		payments = append(payments, &payment)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// AnonymizeUser unlinks payments from the user. The records themselves stay,
+// we have to keep them for accounting. They move to tombstone, an id unique to
+// the erasure, so they stay grouped without naming anyone and never mix with
+// another erased user's.
+func (m *mongoPaymentRepo) AnonymizeUser(ctx context.Context, userID, tombstone string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":    tombstone,
+			"updated_at": time.Now(),
+		},
+	}
+	_, err := m.col.UpdateMany(ctx, bson.M{"user_id": userID}, update)
+	return err
+}
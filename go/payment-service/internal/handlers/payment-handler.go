@@ -174,6 +174,7 @@ func (h *PaymentHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		kafkaEvent := kafka.PaymentCaptured{
 			PaymentID:  event.PaymentID,
 			OrderID:    event.OrderID,
+			UserID:     event.UserID,
 			Amount:     event.Amount,
 			Currency:   event.Currency,
 			CapturedAt: time.Now(),
@@ -184,6 +185,7 @@ func (h *PaymentHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		kafkaEvent := kafka.PaymentFailed{
 			PaymentID: event.PaymentID,
 			OrderID:   event.OrderID,
+			UserID:    event.UserID,
 			Reason:    event.FailReason,
 			FailedAt:  time.Now(),
 		}
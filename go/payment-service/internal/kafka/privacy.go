@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"payment-service/internal/database"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const serviceName = "payment-service"
+
+type UserExportRequested struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+}
+
+type UserDeletionRequested struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+}
+
+type UserDataExported struct {
+	JobID   string          `json:"job_id"`
+	UserID  string          `json:"user_id"`
+	Service string          `json:"service"`
+	Data    json.RawMessage `json:"data"`
+	Time    time.Time       `json:"time"`
+}
+
+type UserDataErased struct {
+	JobID   string    `json:"job_id"`
+	UserID  string    `json:"user_id"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+}
+
+// PrivacyConsumer answers user-service export and erasure jobs.
+type PrivacyConsumer struct {
+	reader      *kafka.Reader
+	writer      *kafka.Writer
+	paymentRepo database.PaymentRepository
+}
+
+func NewPrivacyConsumer(brokers []string, userTopic, privacyTopic, groupId string, repo database.PaymentRepository) *PrivacyConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   userTopic,
+		GroupID: groupId,
+	})
+	return &PrivacyConsumer{
+		reader: reader,
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    privacyTopic,
+			Balancer: &kafka.LeastBytes{},
+		},
+		paymentRepo: repo,
+	}
+}
+
+func (c *PrivacyConsumer) Consume(ctx context.Context) error {
+	log.Println("PrivacyConsumer listening for user events")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("PrivacyConsumer stopping")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetch messaage", err)
+				continue
+			}
+
+			if err := c.ProcessMessages(ctx, msg); err != nil {
+				log.Println("Error processing messages", err)
+			} else {
+				if err := c.reader.CommitMessages(ctx, msg); err != nil {
+					log.Println("Failed to commit message: ", err)
+				}
+			}
+		}
+	}
+}
+
+func (c *PrivacyConsumer) ProcessMessages(ctx context.Context, msg kafka.Message) error {
+	var eventType string
+	for _, h := range msg.Headers {
+		if h.Key == "event" {
+			eventType = string(h.Value)
+			break
+		}
+	}
+
+	switch eventType {
+	case "UserExportRequested":
+		var evt UserExportRequested
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return err
+		}
+		payments, err := c.paymentRepo.GetPaymentsByUserID(ctx, evt.UserID)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(payments)
+		if err != nil {
+			return err
+		}
+		log.Printf("[UserExportRequested] job %s, %d payments", evt.JobID, len(payments))
+		return c.send(ctx, evt.UserID, "UserDataExported", UserDataExported{
+			JobID:   evt.JobID,
+			UserID:  evt.UserID,
+			Service: serviceName,
+			Data:    data,
+			Time:    time.Now(),
+		})
+
+	case "UserDeletionRequested":
+		var evt UserDeletionRequested
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return err
+		}
+		// payments are kept for accounting, just not linked to the user anymore.
+		// the tombstone comes from the job, a redelivery reuses it
+		if err := c.paymentRepo.AnonymizeUser(ctx, evt.UserID, "erased:"+evt.JobID); err != nil {
+			return err
+		}
+		log.Printf("[UserDeletionRequested] job %s anonymized", evt.JobID)
+		return c.send(ctx, evt.UserID, "UserDataErased", UserDataErased{
+			JobID:   evt.JobID,
+			UserID:  evt.UserID,
+			Service: serviceName,
+			Time:    time.Now(),
+		})
+
+	default:
+		return nil
+	}
+}
+
+func (c *PrivacyConsumer) send(ctx context.Context, key, eventName string, evt any) error {
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return c.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(key),
+		Value: b,
+		Headers: []kafka.Header{
+			{Key: "event", Value: []byte(eventName)},
+		},
+	})
+}
+
+func (c *PrivacyConsumer) Close() error {
+	if err := c.reader.Close(); err != nil {
+		return err
+	}
+	return c.writer.Close()
+}
@@ -21,6 +21,7 @@ type PaymentInitiated struct {
 type PaymentCaptured struct {
 	PaymentID  string    `json:"payment_id"`
 	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id"`
 	Amount     int64     `json:"amount"`
 	Currency   string    `json:"currency"`
 	CapturedAt time.Time `json:"captured_at"`
@@ -29,6 +30,7 @@ type PaymentCaptured struct {
 type PaymentFailed struct {
 	PaymentID string    `json:"payment_id"`
 	OrderID   string    `json:"order_id"`
+	UserID    string    `json:"user_id"`
 	Reason    string    `json:"reason"`
 	FailedAt  time.Time `json:"failed_at"`
 }
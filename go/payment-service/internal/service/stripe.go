@@ -137,6 +137,7 @@ func (s *StripeProvider) handlePaymentFailed(raw json.RawMessage) (*models.WebHo
 	event := kafka.PaymentFailed{
 		PaymentID: intent.ID,
 		OrderID:   intent.Metadata["order_id"],
+		UserID:    intent.Metadata["user_id"],
 		Reason:    failReason,
 		FailedAt:  time.Now(),
 	}
@@ -0,0 +1,177 @@
+// catalog imports and exports products in bulk through the api.
+//
+//	catalog import -file products.csv
+//	catalog export -format ndjson > products.ndjson
+//
+// CATALOG_URL is the gateway (default http://localhost:8080) and CATALOG_TOKEN
+// an admin's auth token.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"product-service/internal/catalog"
+	"product-service/internal/models"
+	"strings"
+	"time"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	log.Fatal("usage: catalog import -file products.csv [-format csv|ndjson]\n       catalog export [-format csv|ndjson] [-out file]")
+}
+
+type client struct {
+	baseURL string
+	token   string
+}
+
+func newClient(fs *flag.FlagSet, args []string) *client {
+	baseURL := os.Getenv("CATALOG_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	c := &client{}
+	fs.StringVar(&c.baseURL, "url", baseURL, "api gateway url")
+	fs.StringVar(&c.token, "token", os.Getenv("CATALOG_TOKEN"), "admin auth token")
+	fs.Parse(args)
+	if c.token == "" {
+		log.Fatal("no token, set CATALOG_TOKEN or pass -token")
+	}
+	c.baseURL = strings.TrimRight(c.baseURL, "/")
+	return c
+}
+
+func (c *client) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.AddCookie(&http.Cookie{Name: "Authorization", Value: c.token})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (c *client) job(resp *http.Response) (*models.ImportJob, error) {
+	defer resp.Body.Close()
+	var job models.ImportJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "csv or ndjson file to import")
+	format := fs.String("format", "", "csv or ndjson, by default from the file extension")
+	c := newClient(fs, args)
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	if *format == "" {
+		*format = catalog.FormatCSV
+		if ext := filepath.Ext(*file); ext == ".ndjson" || ext == ".jsonl" {
+			*format = catalog.FormatNDJSON
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	resp, err := c.do(http.MethodPost, "/catalog/import?format="+url.QueryEscape(*format), "", f)
+	if err != nil {
+		log.Fatalf("starting import: %v", err)
+	}
+	job, err := c.job(resp)
+	if err != nil {
+		log.Fatalf("reading import job: %v", err)
+	}
+	log.Printf("import %s started, %d rows", job.ID.Hex(), job.Total)
+
+	for job.Status == models.ImportRunning {
+		time.Sleep(time.Second)
+		resp, err := c.do(http.MethodGet, "/catalog/import/"+job.ID.Hex(), "", nil)
+		if err != nil {
+			log.Fatalf("polling import: %v", err)
+		}
+		if job, err = c.job(resp); err != nil {
+			log.Fatalf("reading import job: %v", err)
+		}
+		log.Printf("%d/%d rows", job.Processed, job.Total)
+	}
+
+	for _, e := range job.Errors {
+		log.Printf("line %d %s: %s", e.Line, e.SKU, e.Error)
+	}
+	if len(job.Errors) < job.Failed {
+		log.Printf("... and %d more errors", job.Failed-len(job.Errors))
+	}
+	log.Printf("%s: %d created, %d updated, %d failed", job.Status, job.Created, job.Updated, job.Failed)
+	if job.Status == models.ImportFailed {
+		log.Fatal(job.Error)
+	}
+	if job.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", catalog.FormatCSV, "csv or ndjson")
+	out := fs.String("out", "", "file to write, stdout by default")
+	c := newClient(fs, args)
+
+	resp, err := c.do(http.MethodGet, "/catalog/export?format="+url.QueryEscape(*format), "", nil)
+	if err != nil {
+		log.Fatalf("exporting: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Fatalf("exporting: %v", err)
+	}
+}
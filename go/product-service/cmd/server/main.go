@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"grpc_module/auth/authpb"
+	"grpc_module/mtls"
 	"grpc_module/product/productpb"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"product-service/internal/cache"
 	"product-service/internal/database"
 	"product-service/internal/handlers"
 	"product-service/internal/kafka"
+	"product-service/internal/search"
+	"product-service/internal/storage"
 	"product-service/logger"
 	"strconv"
 	"strings"
@@ -20,7 +25,6 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -38,6 +42,10 @@ func main() {
 	topic := os.Getenv("KAFKA_TOPIC")
 	brokersENV := os.Getenv("KAFKA_BROKERS")
 	brokers := strings.Split(brokersENV, ",")
+	paymentTopic := os.Getenv("PAYMENT_TOPIC")
+	if paymentTopic == "" {
+		paymentTopic = "payment-service"
+	}
 
 	// init logger
 	logMode, err := strconv.ParseBool(logDev)
@@ -57,8 +65,74 @@ func main() {
 	if err != nil {
 		log.Fatalf("couldnt connect to mongodb: %v", err)
 	}
-	repo := database.NewMongoRepo(cl, dbName)
-	authConn, err := grpc.NewClient("localhost:"+authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	var repo database.ProductRepository = database.NewMongoRepo(cl, dbName)
+	var inventoryRepo database.InventoryRepository = database.NewMongoInventoryRepo(cl, dbName)
+	cacheStore, cacheTTL, err := cache.FromEnv()
+	if err != nil {
+		log.Fatalf("couldnt init product cache: %v", err)
+	}
+	var productCache *cache.Products
+	if cacheStore != nil {
+		productCache = cache.NewProducts(repo, cacheStore, cacheTTL)
+		repo = productCache
+		inventoryRepo = cache.NewInventory(inventoryRepo, productCache)
+	}
+	categoryRepo := database.NewMongoCategoryRepo(cl, dbName)
+	reviewRepo := database.NewMongoReviewRepo(cl, dbName)
+	importRepo := database.NewMongoImportJobRepo(cl, dbName)
+	priceRepo := database.NewMongoPriceRepo(cl, dbName)
+	recsRepo := database.NewMongoRecommendationRepo(cl, dbName)
+	searchLogRepo := database.NewMongoSearchLogRepo(cl, dbName)
+	searchEngine := search.NewMongoEngine(cl, dbName, repo)
+	suggester := search.NewSuggester(repo, categoryRepo, searchLogRepo)
+	blobStore, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("couldnt init blob store: %v", err)
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		n, err := searchEngine.Backfill(ctx)
+		if err != nil {
+			log.Printf("search ngram backfill failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("search ngram backfill updated %d products", n)
+		}
+	}()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		n, err := repo.BackfillRatings(ctx)
+		if err != nil {
+			log.Printf("rating backfill failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("rating backfill updated %d products", n)
+		}
+	}()
+	// import jobs run in this process, one still running here died with the last one
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		n, err := importRepo.FailRunning(ctx, "interrupted by a restart, import the file again")
+		if err != nil {
+			log.Printf("failing interrupted import jobs: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("marked %d interrupted import jobs as failed", n)
+		}
+	}()
+
+	tlsCfg := mtls.FromEnv()
+	authCreds, err := mtls.ClientCredentials(tlsCfg, "auth-service")
+	if err != nil {
+		log.Fatalf("couldnt load mtls client config: %v", err)
+	}
+	authConn, err := grpc.NewClient("localhost:"+authAddr, grpc.WithTransportCredentials(authCreds))
 	if err != nil {
 		log.Fatalf("couldnt connect to authservice grpc: %v", err)
 	}
@@ -66,13 +140,85 @@ func main() {
 
 	authClient := authpb.NewAuthServiceClient(authConn)
 	productProducer := kafka.NewProductProducer(brokers, topic)
-	productHandler := handlers.NewProductHandler(repo, logger, authClient, productProducer)
+	productHandler := handlers.NewProductHandler(repo, inventoryRepo, categoryRepo, reviewRepo, importRepo, priceRepo, recsRepo, searchLogRepo, searchEngine, suggester, blobStore, logger, authClient, productProducer)
+
+	// stock held by checkouts that never finished goes back after its ttl
+	go productHandler.SweepReservations(context.Background(), time.Minute)
+	// scheduled prices and sales
+	go productHandler.RunPriceScheduler(context.Background(), 30*time.Second)
+	// autocomplete, rebuilt on product events and for search popularity
+	go suggester.Run(context.Background(), 10*time.Minute)
+	// frequently bought together from paid orders
+	go productHandler.RunRecommendations(context.Background(), 6*time.Hour)
+
+	// commit / release reservations when payments settle
+	paymentConsumer := kafka.NewPaymentConsumer(brokers, paymentTopic, "product-service-payment-group", productHandler)
+	defer paymentConsumer.Close()
+	go func() {
+		if err := paymentConsumer.Consume(context.Background()); err != nil {
+			log.Printf("payment consumer err: %v", err)
+		}
+	}()
+
+	// keep product ratings in sync with approved reviews
+	reviewConsumer := kafka.NewReviewConsumer(brokers, topic, "product-service-review-group", reviewRepo, repo, productProducer)
+	defer reviewConsumer.Close()
+	go func() {
+		if err := reviewConsumer.Consume(context.Background()); err != nil {
+			log.Printf("review consumer err: %v", err)
+		}
+	}()
+
+	// a replica's own lru misses what the others change, a shared redis doesn't
+	if _, ok := cacheStore.(*cache.LRU); ok {
+		groupID := os.Getenv("CACHE_GROUP_ID")
+		if groupID == "" {
+			host, _ := os.Hostname()
+			groupID = "product-service-cache-" + host
+		}
+		cacheConsumer := kafka.NewCacheConsumer(brokers, topic, groupID, productCache)
+		defer cacheConsumer.Close()
+		go func() {
+			if err := cacheConsumer.Consume(context.Background()); err != nil {
+				log.Printf("cache consumer err: %v", err)
+			}
+		}()
+	}
+
+	suggestGroupID := os.Getenv("SUGGEST_GROUP_ID")
+	if suggestGroupID == "" {
+		host, _ := os.Hostname()
+		suggestGroupID = "product-service-suggest-" + host
+	}
+	suggestConsumer := kafka.NewSuggestConsumer(brokers, topic, suggestGroupID, suggester)
+	defer suggestConsumer.Close()
+	go func() {
+		if err := suggestConsumer.Consume(context.Background()); err != nil {
+			log.Printf("suggest consumer err: %v", err)
+		}
+	}()
 
 	// http handler
 	http.Handle("/api/", productHandler.Routes())
 
 	// grpc
-	server := grpc.NewServer(grpc.Creds(insecure.NewCredentials()))
+	allow := mtls.AllowList{
+		"/product.ProductService/GetProductById":     {"cart-service"},
+		"/product.ProductService/BatchGetProducts":   {"cart-service", "order-service"},
+		"/product.ProductService/ListProducts":       {"cart-service", "order-service"},
+		"/product.ProductService/ReserveStock":       {"order-service"},
+		"/product.ProductService/CommitReservation":  {"order-service"},
+		"/product.ProductService/ReleaseReservation": {"order-service"},
+		// catalog writes are for admin tooling only
+		"/product.ProductService/CreateProduct": {mtls.DevClient},
+		"/product.ProductService/UpdateProduct": {mtls.DevClient},
+		"/product.ProductService/DeleteProduct": {mtls.DevClient},
+	}.WithReflection(mtls.DevClient)
+	serverOpts, err := mtls.ServerOptions(tlsCfg, allow)
+	if err != nil {
+		log.Fatalf("couldnt load mtls server config: %v", err)
+	}
+	server := grpc.NewServer(serverOpts...)
 	productpb.RegisterProductServiceServer(server, productHandler)
 	reflection.Register(server)
 
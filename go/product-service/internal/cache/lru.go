@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process store of at most size entries, the least recently
+// used one goes first. Each replica has its own, see the cache consumer for
+// keeping them in sync.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+	// versions: clock ticks on every invalidation, gens has the tick a key was
+	// last deleted at and purgedAt the last purge, which also resets gens
+	clock    uint64
+	gens     map[string]uint64
+	purgedAt uint64
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{size: size, order: list.New(), items: map[string]*list.Element{}, gens: map[string]uint64{}}
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (c *LRU) Versions(_ context.Context, keys ...string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	versions := make([]string, len(keys))
+	for i, key := range keys {
+		versions[i] = c.version(key)
+	}
+	return versions, nil
+}
+
+func (c *LRU) version(key string) string {
+	return strconv.FormatUint(max(c.gens[key], c.purgedAt), 10)
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version(key) != version {
+		return nil
+	}
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		evictions.Add(1)
+	}
+	return nil
+}
+
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.clock++
+		c.gens[key] = c.clock
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+func (c *LRU) Purge(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = map[string]*list.Element{}
+	c.clock++
+	c.purgedAt = c.clock
+	c.gens = map[string]uint64{}
+	return nil
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*lruEntry).key)
+}
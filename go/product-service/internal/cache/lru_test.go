@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func mustGet(t *testing.T, c Store, key string) ([]byte, bool) {
+	t.Helper()
+	value, ok, err := c.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	return value, ok
+}
+
+func mustSet(t *testing.T, c Store, key, value string, ttl time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	versions, err := c.Versions(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, key, []byte(value), ttl, versions[0]); err != nil {
+		t.Fatalf("set %s: %v", key, err)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+	before := evictions.Value()
+
+	mustSet(t, c, "a", "1", time.Minute)
+	mustSet(t, c, "b", "2", time.Minute)
+	// a is now more recent than b
+	if _, ok := mustGet(t, c, "a"); !ok {
+		t.Fatal("a missing before eviction")
+	}
+	mustSet(t, c, "c", "3", time.Minute)
+
+	if _, ok := mustGet(t, c, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := mustGet(t, c, key); !ok {
+			t.Errorf("%s evicted, want b to go", key)
+		}
+	}
+	if got := evictions.Value() - before; got != 1 {
+		t.Errorf("evictions went up by %d, want 1", got)
+	}
+
+	// overwriting a key doesn't count against the size
+	mustSet(t, c, "c", "4", time.Minute)
+	if value, _ := mustGet(t, c, "c"); string(value) != "4" {
+		t.Errorf("c = %q, want 4", value)
+	}
+	if _, ok := mustGet(t, c, "a"); !ok {
+		t.Error("overwrite evicted a")
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	c := NewLRU(10)
+	mustSet(t, c, "short", "1", 10*time.Millisecond)
+	mustSet(t, c, "long", "2", time.Minute)
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := mustGet(t, c, "short"); ok {
+		t.Error("expired entry was returned")
+	}
+	if _, ok := c.items["short"]; ok {
+		t.Error("expired entry wasn't removed on read")
+	}
+	if _, ok := mustGet(t, c, "long"); !ok {
+		t.Error("live entry missing")
+	}
+}
+
+func TestLRUSetSkipsInvalidatedKeys(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	versions, _ := c.Versions(ctx, "a", "b")
+	// a write lands while a and b are being read
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "a", []byte("stale"), time.Minute, versions[0])
+	c.Set(ctx, "b", []byte("fresh"), time.Minute, versions[1])
+	if _, ok := mustGet(t, c, "a"); ok {
+		t.Error("read that raced a delete was stored")
+	}
+	if _, ok := mustGet(t, c, "b"); !ok {
+		t.Error("delete of a kept b from being stored")
+	}
+
+	// a read that starts after the delete is fine
+	mustSet(t, c, "a", "fresh", time.Minute)
+	if _, ok := mustGet(t, c, "a"); !ok {
+		t.Error("read after the delete wasn't stored")
+	}
+
+	versions, _ = c.Versions(ctx, "c")
+	if err := c.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "c", []byte("stale"), time.Minute, versions[0])
+	if _, ok := mustGet(t, c, "c"); ok {
+		t.Error("read that raced a purge was stored")
+	}
+	if _, ok := mustGet(t, c, "a"); ok {
+		t.Error("purge left a behind")
+	}
+}
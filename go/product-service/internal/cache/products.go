@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func productKey(id string) string {
+	return "product:" + id
+}
+
+// Products is a read-through cache in front of GetProductById and
+// GetProductsByIds. Entries are stored encoded so callers can't change a
+// cached product, writes through it drop the product's entry. Changes made
+// by other replicas come in as events, see Invalidate. A read that raced an
+// invalidation isn't stored, see Store.
+type Products struct {
+	database.ProductRepository
+	store Store
+	ttl   time.Duration
+}
+
+func NewProducts(repo database.ProductRepository, store Store, ttl time.Duration) *Products {
+	return &Products{ProductRepository: repo, store: store, ttl: ttl}
+}
+
+// Invalidate drops the products' entries. A store error is only logged, the
+// entry then lives until its ttl.
+func (c *Products) Invalidate(ctx context.Context, ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productKey(id)
+	}
+	if err := c.store.Delete(ctx, keys...); err != nil {
+		errs.Add(1)
+		log.Printf("[cache] err invalidating %v: %v", ids, err)
+		return
+	}
+	invalidations.Add(int64(len(ids)))
+}
+
+// Purge drops every cached product.
+func (c *Products) Purge(ctx context.Context) {
+	if err := c.store.Purge(ctx); err != nil {
+		errs.Add(1)
+		log.Printf("[cache] err purging: %v", err)
+		return
+	}
+	invalidations.Add(1)
+}
+
+func (c *Products) get(ctx context.Context, id string) *models.Product {
+	data, ok, err := c.store.Get(ctx, productKey(id))
+	if err != nil {
+		errs.Add(1)
+		log.Printf("[cache] err reading %s: %v", id, err)
+	}
+	if !ok {
+		misses.Add(1)
+		return nil
+	}
+	var p models.Product
+	if err := bson.Unmarshal(data, &p); err != nil {
+		errs.Add(1)
+		log.Printf("[cache] dropping undecodable %s: %v", id, err)
+		c.store.Delete(ctx, productKey(id))
+		misses.Add(1)
+		return nil
+	}
+	hits.Add(1)
+	return &p
+}
+
+// versions takes the versions of the products about to be read from the
+// database. On error nothing read is stored, see Store.
+func (c *Products) versions(ctx context.Context, ids []string) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productKey(id)
+	}
+	versions, err := c.store.Versions(ctx, keys...)
+	if err != nil {
+		errs.Add(1)
+		log.Printf("[cache] err reading versions of %v: %v", ids, err)
+		return nil
+	}
+	return versions
+}
+
+func (c *Products) set(ctx context.Context, p *models.Product, version string) {
+	// the repo hands back an empty product for some lookups that failed
+	if p == nil || p.ID.IsZero() {
+		return
+	}
+	data, err := bson.Marshal(p)
+	if err == nil {
+		err = c.store.Set(ctx, productKey(p.ID.Hex()), data, c.ttl, version)
+	}
+	if err != nil {
+		errs.Add(1)
+		log.Printf("[cache] err storing %s: %v", p.ID.Hex(), err)
+	}
+}
+
+func (c *Products) GetProductById(ctx context.Context, id string) (*models.Product, error) {
+	if p := c.get(ctx, id); p != nil {
+		return p, nil
+	}
+	versions := c.versions(ctx, []string{id})
+	p, err := c.ProductRepository.GetProductById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if versions != nil && p != nil && p.ID.Hex() == id {
+		c.set(ctx, p, versions[0])
+	}
+	return p, nil
+}
+
+func (c *Products) GetProductsByIds(ctx context.Context, ids []string) ([]*models.Product, error) {
+	products := make([]*models.Product, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		if p := c.get(ctx, id); p != nil {
+			products = append(products, p)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return products, nil
+	}
+	versions := c.versions(ctx, missing)
+	found, err := c.ProductRepository.GetProductsByIds(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if versions != nil {
+		byID := make(map[string]string, len(missing))
+		for i, id := range missing {
+			byID[id] = versions[i]
+		}
+		for _, p := range found {
+			if version, ok := byID[p.ID.Hex()]; ok {
+				c.set(ctx, p, version)
+			}
+		}
+	}
+	return append(products, found...), nil
+}
+
+func (c *Products) UpdateProduct(ctx context.Context, id string, changes map[string]interface{}, expectedVersion *int64) (*models.Product, error) {
+	p, err := c.ProductRepository.UpdateProduct(ctx, id, changes, expectedVersion)
+	c.Invalidate(ctx, id)
+	return p, err
+}
+
+func (c *Products) DeleteProduct(ctx context.Context, id string) (*models.Product, error) {
+	p, err := c.ProductRepository.DeleteProduct(ctx, id)
+	c.Invalidate(ctx, id)
+	return p, err
+}
+
+func (c *Products) SetVariants(ctx context.Context, id string, options []models.ProductOption, variants []models.Variant, version int64) (*models.Product, error) {
+	p, err := c.ProductRepository.SetVariants(ctx, id, options, variants, version)
+	c.Invalidate(ctx, id)
+	return p, err
+}
+
+func (c *Products) SetGallery(ctx context.Context, id string, gallery []models.GalleryImage, version int64) (*models.Product, error) {
+	p, err := c.ProductRepository.SetGallery(ctx, id, gallery, version)
+	c.Invalidate(ctx, id)
+	return p, err
+}
+
+func (c *Products) SetRating(ctx context.Context, id string, rating models.RatingSummary) error {
+	err := c.ProductRepository.SetRating(ctx, id, rating)
+	c.Invalidate(ctx, id)
+	return err
+}
+
+// BackfillRatings touches any number of products too.
+func (c *Products) BackfillRatings(ctx context.Context) (int64, error) {
+	n, err := c.ProductRepository.BackfillRatings(ctx)
+	if n > 0 {
+		c.Purge(ctx)
+	}
+	return n, err
+}
+
+// RenameCategory touches any number of products, it's rare enough to drop them all.
+func (c *Products) RenameCategory(ctx context.Context, categoryID, name string) (int64, error) {
+	n, err := c.ProductRepository.RenameCategory(ctx, categoryID, name)
+	if n > 0 {
+		c.Purge(ctx)
+	}
+	return n, err
+}
+
+// Inventory drops the cached product whenever its stock moves, the stock is
+// part of the product document.
+type Inventory struct {
+	database.InventoryRepository
+	products *Products
+}
+
+func NewInventory(repo database.InventoryRepository, products *Products) *Inventory {
+	return &Inventory{InventoryRepository: repo, products: products}
+}
+
+func (c *Inventory) AdjustStock(ctx context.Context, productID string, movement *models.StockMovement) (*models.Inventory, error) {
+	stock, err := c.InventoryRepository.AdjustStock(ctx, productID, movement)
+	c.products.Invalidate(ctx, productID)
+	return stock, err
+}
+
+// a failed reservation may still have held some lines before giving them back
+func (c *Inventory) ReserveStock(ctx context.Context, orderID string, lines []models.ReservationLine, ttl time.Duration) (*models.Reservation, []*models.Inventory, error) {
+	res, stocks, err := c.InventoryRepository.ReserveStock(ctx, orderID, lines, ttl)
+	c.products.Invalidate(ctx, lineProducts(lines)...)
+	return res, stocks, err
+}
+
+func (c *Inventory) CommitReservation(ctx context.Context, orderID string) (*models.Reservation, []*models.Inventory, error) {
+	res, stocks, err := c.InventoryRepository.CommitReservation(ctx, orderID)
+	if res != nil {
+		c.products.Invalidate(ctx, lineProducts(res.Lines)...)
+	}
+	return res, stocks, err
+}
+
+func (c *Inventory) ReleaseReservation(ctx context.Context, orderID string, status models.ReservationStatus, reason string) (*models.Reservation, []*models.Inventory, error) {
+	res, stocks, err := c.InventoryRepository.ReleaseReservation(ctx, orderID, status, reason)
+	if res != nil {
+		c.products.Invalidate(ctx, lineProducts(res.Lines)...)
+	}
+	return res, stocks, err
+}
+
+func lineProducts(lines []models.ReservationLine) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, l := range lines {
+		if !seen[l.ProductID] {
+			seen[l.ProductID] = true
+			ids = append(ids, l.ProductID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeRepo serves products from a map and counts database reads. Methods the
+// cache doesn't wrap panic through the nil embedded interface.
+type fakeRepo struct {
+	database.ProductRepository
+	products map[string]*models.Product
+	reads    int
+	// runs in the middle of a read, like a write from another request would
+	duringRead func()
+}
+
+func newFakeRepo(products ...*models.Product) *fakeRepo {
+	repo := &fakeRepo{products: map[string]*models.Product{}}
+	for _, p := range products {
+		repo.products[p.ID.Hex()] = p
+	}
+	return repo
+}
+
+func (r *fakeRepo) read(id string) *models.Product {
+	p, ok := r.products[id]
+	if !ok {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
+
+func (r *fakeRepo) GetProductById(_ context.Context, id string) (*models.Product, error) {
+	r.reads++
+	p := r.read(id)
+	if r.duringRead != nil {
+		r.duringRead()
+	}
+	return p, nil
+}
+
+func (r *fakeRepo) GetProductsByIds(_ context.Context, ids []string) ([]*models.Product, error) {
+	r.reads++
+	var products []*models.Product
+	for _, id := range ids {
+		if p := r.read(id); p != nil {
+			products = append(products, p)
+		}
+	}
+	if r.duringRead != nil {
+		r.duringRead()
+	}
+	return products, nil
+}
+
+func (r *fakeRepo) UpdateProduct(_ context.Context, id string, changes map[string]interface{}, _ *int64) (*models.Product, error) {
+	if name, ok := changes["name"].(string); ok {
+		r.products[id].Name = name
+	}
+	return r.read(id), nil
+}
+
+func (r *fakeRepo) DeleteProduct(_ context.Context, id string) (*models.Product, error) {
+	p := r.read(id)
+	delete(r.products, id)
+	return p, nil
+}
+
+func (r *fakeRepo) SetVariants(_ context.Context, id string, _ []models.ProductOption, _ []models.Variant, _ int64) (*models.Product, error) {
+	return r.read(id), nil
+}
+
+func (r *fakeRepo) SetGallery(_ context.Context, id string, _ []models.GalleryImage, _ int64) (*models.Product, error) {
+	return r.read(id), nil
+}
+
+func (r *fakeRepo) SetRating(_ context.Context, id string, rating models.RatingSummary) error {
+	r.products[id].Rating = rating
+	return nil
+}
+
+func (r *fakeRepo) BackfillRatings(context.Context) (int64, error) {
+	return int64(len(r.products)), nil
+}
+
+func (r *fakeRepo) RenameCategory(_ context.Context, categoryID, name string) (int64, error) {
+	var n int64
+	for _, p := range r.products {
+		if p.CategoryID == categoryID {
+			p.Category = name
+			n++
+		}
+	}
+	return n, nil
+}
+
+type fakeInventory struct {
+	database.InventoryRepository
+}
+
+func (fakeInventory) AdjustStock(context.Context, string, *models.StockMovement) (*models.Inventory, error) {
+	return &models.Inventory{}, nil
+}
+
+func (fakeInventory) ReserveStock(_ context.Context, orderID string, lines []models.ReservationLine, _ time.Duration) (*models.Reservation, []*models.Inventory, error) {
+	return &models.Reservation{OrderID: orderID, Lines: lines}, nil, nil
+}
+
+func (fakeInventory) CommitReservation(_ context.Context, orderID string) (*models.Reservation, []*models.Inventory, error) {
+	return nil, nil, nil
+}
+
+func newProduct(name string) *models.Product {
+	return &models.Product{ID: primitive.NewObjectID(), Name: name, CategoryID: "cat", Category: "Shoes"}
+}
+
+func TestProductsReadThrough(t *testing.T) {
+	ctx := context.Background()
+	p := newProduct("boot")
+	repo := newFakeRepo(p)
+	c := NewProducts(repo, NewLRU(10), time.Minute)
+	id := p.ID.Hex()
+
+	first, err := c.GetProductById(ctx, id)
+	if err != nil || first == nil || first.Name != "boot" {
+		t.Fatalf("first read = %+v, %v", first, err)
+	}
+	// callers can't change what's cached
+	first.Name = "changed"
+	second, _ := c.GetProductById(ctx, id)
+	if repo.reads != 1 {
+		t.Errorf("%d database reads, want 1", repo.reads)
+	}
+	if second.Name != "boot" {
+		t.Errorf("cached name = %q, a caller's change leaked in", second.Name)
+	}
+
+	// unknown ids aren't cached
+	if got, _ := c.GetProductById(ctx, primitive.NewObjectID().Hex()); got != nil {
+		t.Errorf("unknown id = %+v", got)
+	}
+}
+
+func TestProductsGetByIdsMixesHitsAndMisses(t *testing.T) {
+	ctx := context.Background()
+	a, b := newProduct("a"), newProduct("b")
+	repo := newFakeRepo(a, b)
+	c := NewProducts(repo, NewLRU(10), time.Minute)
+
+	c.GetProductById(ctx, a.ID.Hex())
+	products, err := c.GetProductsByIds(ctx, []string{a.ID.Hex(), b.ID.Hex()})
+	if err != nil || len(products) != 2 {
+		t.Fatalf("got %d products, %v", len(products), err)
+	}
+	// a from the cache, b from the database, then both cached
+	c.GetProductsByIds(ctx, []string{a.ID.Hex(), b.ID.Hex()})
+	if repo.reads != 2 {
+		t.Errorf("%d database reads, want 2", repo.reads)
+	}
+}
+
+func TestProductsWritesInvalidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, c *Products, id string)
+	}{
+		{"UpdateProduct", func(ctx context.Context, c *Products, id string) {
+			c.UpdateProduct(ctx, id, map[string]interface{}{"name": "new"}, nil)
+		}},
+		{"DeleteProduct", func(ctx context.Context, c *Products, id string) {
+			c.DeleteProduct(ctx, id)
+		}},
+		{"SetVariants", func(ctx context.Context, c *Products, id string) {
+			c.SetVariants(ctx, id, nil, nil, 0)
+		}},
+		{"SetGallery", func(ctx context.Context, c *Products, id string) {
+			c.SetGallery(ctx, id, nil, 0)
+		}},
+		{"SetRating", func(ctx context.Context, c *Products, id string) {
+			c.SetRating(ctx, id, models.RatingSummary{Average: 4, Count: 1})
+		}},
+		{"BackfillRatings", func(ctx context.Context, c *Products, id string) {
+			c.BackfillRatings(ctx)
+		}},
+		{"RenameCategory", func(ctx context.Context, c *Products, id string) {
+			c.RenameCategory(ctx, "cat", "Boots")
+		}},
+		{"Inventory.AdjustStock", func(ctx context.Context, c *Products, id string) {
+			NewInventory(fakeInventory{}, c).AdjustStock(ctx, id, &models.StockMovement{Delta: 1})
+		}},
+		{"Inventory.ReserveStock", func(ctx context.Context, c *Products, id string) {
+			NewInventory(fakeInventory{}, c).ReserveStock(ctx, "order", []models.ReservationLine{{ProductID: id, Quantity: 1}}, time.Minute)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p := newProduct("boot")
+			repo := newFakeRepo(p)
+			c := NewProducts(repo, NewLRU(10), time.Minute)
+			id := p.ID.Hex()
+
+			c.GetProductById(ctx, id)
+			tt.write(ctx, c, id)
+			c.GetProductById(ctx, id)
+			if repo.reads != 2 {
+				t.Errorf("%d database reads, want the write to drop the cached product", repo.reads)
+			}
+		})
+	}
+}
+
+func TestInventoryCommitWithoutReservation(t *testing.T) {
+	c := NewProducts(newFakeRepo(), NewLRU(10), time.Minute)
+	// nothing to invalidate, mustn't panic on the nil reservation
+	if _, _, err := NewInventory(fakeInventory{}, c).CommitReservation(context.Background(), "order"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductsSkipsReadsThatRacedAWrite(t *testing.T) {
+	ctx := context.Background()
+	p := newProduct("old")
+	repo := newFakeRepo(p)
+	c := NewProducts(repo, NewLRU(10), time.Minute)
+	id := p.ID.Hex()
+
+	// the read gets the old document, then a write lands and invalidates
+	// before the read stores it
+	repo.duringRead = func() {
+		repo.duringRead = nil
+		c.UpdateProduct(ctx, id, map[string]interface{}{"name": "new"}, nil)
+	}
+	if got, _ := c.GetProductById(ctx, id); got.Name != "old" {
+		t.Fatalf("racing read = %q, want the old document", got.Name)
+	}
+	if got, _ := c.GetProductById(ctx, id); got.Name != "new" {
+		t.Errorf("after the write = %q, the stale read was cached", got.Name)
+	}
+
+	q := newProduct("old")
+	repo.products[q.ID.Hex()] = q
+	repo.duringRead = func() {
+		repo.duringRead = nil
+		c.UpdateProduct(ctx, q.ID.Hex(), map[string]interface{}{"name": "new"}, nil)
+	}
+	c.GetProductsByIds(ctx, []string{q.ID.Hex()})
+	products, _ := c.GetProductsByIds(ctx, []string{q.ID.Hex()})
+	if len(products) != 1 || products[0].Name != "new" {
+		t.Errorf("after the write = %+v, the stale batch read was cached", products)
+	}
+}
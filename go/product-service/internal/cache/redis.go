@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// every key of this service starts with it, Purge only removes those
+const redisPrefix = "product-service:"
+
+// versions live outside redisPrefix so Purge doesn't reset them. They expire
+// long after any read that took them has finished, a counter going back to
+// zero before that could let a stale read through.
+const (
+	redisVersionPrefix = "product-service-version:"
+	redisPurgeVersion  = redisVersionPrefix + "purge"
+	redisVersionTTL    = time.Hour
+)
+
+// KEYS: value key, version key, purge version. ARGV: value, ttl in ms,
+// version as "<purge>:<key>".
+var redisSetIfVersion = redis.NewScript(`
+local purge = redis.call("GET", KEYS[3]) or "0"
+local ver = redis.call("GET", KEYS[2]) or "0"
+if purge .. ":" .. ver == ARGV[3] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+end
+return 0
+`)
+
+// Redis is shared by all replicas, an invalidation is seen by every one.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(addr, password string, db int) (*Redis, error) {
+	if addr == "" {
+		return nil, errors.New("REDIS_ADDR is required for the redis cache")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return &Redis{client: client}, nil
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, redisPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Versions(ctx context.Context, keys ...string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	versionKeys := make([]string, 0, len(keys)+1)
+	versionKeys = append(versionKeys, redisPurgeVersion)
+	for _, key := range keys {
+		versionKeys = append(versionKeys, redisVersionPrefix+key)
+	}
+	values, err := c.client.MGet(ctx, versionKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	purge := versionOrZero(values[0])
+	versions := make([]string, len(keys))
+	for i := range keys {
+		versions[i] = purge + ":" + versionOrZero(values[i+1])
+	}
+	return versions, nil
+}
+
+func versionOrZero(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return "0"
+}
+
+// Set compares and sets in one script, an invalidation can't slip in between.
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, version string) error {
+	keys := []string{redisPrefix + key, redisVersionPrefix + key, redisPurgeVersion}
+	return redisSetIfVersion.Run(ctx, c.client, keys, value, ttl.Milliseconds(), version).Err()
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	pipe := c.client.TxPipeline()
+	for i, key := range keys {
+		prefixed[i] = redisPrefix + key
+		pipe.Incr(ctx, redisVersionPrefix+key)
+		pipe.Expire(ctx, redisVersionPrefix+key, redisVersionTTL)
+	}
+	pipe.Del(ctx, prefixed...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Purge scans for the service's keys, it is only used for rare bulk changes.
+// The purge version moves first so reads already running don't store what
+// they got.
+func (c *Redis) Purge(ctx context.Context) error {
+	pipe := c.client.TxPipeline()
+	pipe.Incr(ctx, redisPurgeVersion)
+	pipe.Expire(ctx, redisPurgeVersion, redisVersionTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	iter := c.client.Scan(ctx, 0, redisPrefix+"*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := c.client.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return c.client.Del(ctx, batch...).Err()
+	}
+	return nil
+}
+
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
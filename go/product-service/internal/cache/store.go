@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Store keeps encoded values for a while. A miss is ok=false, errors are for
+// a store that can't be reached.
+//
+// Every Delete and Purge moves the version of the keys it drops. A read-through
+// takes the versions before reading the database and hands them to Set, which
+// skips the write when an invalidation came in between, so a stale read can't
+// land after the write that made it stale.
+type Store interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Versions(ctx context.Context, keys ...string) ([]string, error)
+	// Set stores value unless key was invalidated since Versions returned version
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, version string) error
+	Delete(ctx context.Context, keys ...string) error
+	// Purge drops everything this service put in the store
+	Purge(ctx context.Context) error
+}
+
+// served on /debug/vars
+var (
+	hits          = expvar.NewInt("product_cache_hits")
+	misses        = expvar.NewInt("product_cache_misses")
+	errs          = expvar.NewInt("product_cache_errors")
+	evictions     = expvar.NewInt("product_cache_evictions")
+	invalidations = expvar.NewInt("product_cache_invalidations")
+)
+
+const (
+	defaultTTL  = 5 * time.Minute
+	defaultSize = 10000
+)
+
+// FromEnv picks the store with CACHE_STORE: lru (default), redis or off, the
+// latter returns a nil Store. CACHE_TTL is a duration like 5m.
+func FromEnv() (Store, time.Duration, error) {
+	ttl := defaultTTL
+	if v := os.Getenv("CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid CACHE_TTL %q", v)
+		}
+		ttl = d
+	}
+
+	switch os.Getenv("CACHE_STORE") {
+	case "", "lru":
+		size := defaultSize
+		if v := os.Getenv("CACHE_SIZE"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, 0, fmt.Errorf("invalid CACHE_SIZE %q", v)
+			}
+			size = n
+		}
+		return NewLRU(size), ttl, nil
+	case "redis":
+		db, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+		store, err := NewRedis(os.Getenv("REDIS_ADDR"), os.Getenv("REDIS_PASSWORD"), db)
+		return store, ttl, err
+	case "off":
+		return nil, ttl, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown CACHE_STORE %q", os.Getenv("CACHE_STORE"))
+	}
+}
@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"product-service/internal/models"
+	"strconv"
+)
+
+// Writer writes products in the import format, so an export can be edited
+// and imported again. Rows carry the product id, which import matches on
+// before the sku, so products with variants and no sku of their own come back
+// as updates too.
+type Writer struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(Columns); err != nil {
+			return nil, err
+		}
+		return &Writer{csv: cw}, nil
+	case FormatNDJSON:
+		return &Writer{json: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, use csv or ndjson", format)
+	}
+}
+
+// Write writes one product. Stock is left empty for products with variants,
+// theirs is per variant and not part of the format.
+func (w *Writer) Write(p *models.Product) error {
+	row := Row{
+		ID:          p.ID.Hex(),
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Description: p.Description,
+		Category:    p.Category,
+		CategoryID:  p.CategoryID,
+		PriceCents:  p.PriceCents,
+		Image:       p.Image,
+	}
+	stock := ""
+	if len(p.Variants) == 0 {
+		row.Stock = &p.Stock.OnHand
+		stock = strconv.FormatInt(p.Stock.OnHand, 10)
+	}
+	if w.json != nil {
+		return w.json.Encode(row)
+	}
+	return w.csv.Write([]string{
+		row.ID,
+		row.SKU,
+		row.Name,
+		row.Description,
+		row.Category,
+		row.CategoryID,
+		strconv.FormatInt(row.PriceCents, 10),
+		stock,
+		row.Image,
+	})
+}
+
+// Flush pushes buffered csv rows out, call it now and then while streaming.
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
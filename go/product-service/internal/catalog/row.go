@@ -0,0 +1,200 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"product-service/internal/models"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	MaxRows = 10000
+)
+
+// Columns of the csv format, in export order. The ndjson format uses the
+// same names as keys.
+var Columns = []string{"id", "sku", "name", "description", "category", "category_id", "price_cents", "stock", "image"}
+
+// Row is one product of an import, matched to existing products by ID when
+// it has one and by SKU otherwise. Rows without either are new products.
+type Row struct {
+	Line int `json:"-"`
+	// set on export, products with variants have no sku to match on
+	ID          string `json:"id,omitempty"`
+	SKU         string `json:"sku"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	CategoryID  string `json:"category_id"`
+	PriceCents  int64  `json:"price_cents"`
+	// nil leaves the stock alone, otherwise on hand is set to it
+	Stock *int64 `json:"stock"`
+	// used as is, the image pipeline only runs for uploads
+	Image string `json:"image"`
+}
+
+func (r *Row) Validate() error {
+	switch {
+	case strings.TrimSpace(r.SKU) == "" && r.ID == "":
+		return errors.New("sku or id is required")
+	case r.ID != "" && !primitive.IsValidObjectID(r.ID):
+		return errors.New("id is not a product id")
+	case strings.TrimSpace(r.Name) == "":
+		return errors.New("name is required")
+	case r.Category == "" && r.CategoryID == "":
+		return errors.New("category or category_id is required")
+	case r.PriceCents <= 0:
+		return errors.New("price_cents must be positive")
+	case r.Stock != nil && *r.Stock < 0:
+		return errors.New("stock can't be negative")
+	case r.Image != "" && !strings.HasPrefix(r.Image, "https://") && !strings.HasPrefix(r.Image, "http://"):
+		return errors.New("image must be an http(s) url")
+	}
+	return nil
+}
+
+// Parse reads every row of the input. Rows that don't parse or validate are
+// returned as errors instead, only a broken file as a whole fails.
+func Parse(format string, r io.Reader) ([]Row, []models.ImportRowError, error) {
+	var rows []Row
+	var rowErrs []models.ImportRowError
+	seen := map[string]int{}
+	add := func(row Row, err error) error {
+		if err == nil {
+			err = row.Validate()
+		}
+		key := "sku:" + row.SKU
+		if row.ID != "" {
+			key = "id:" + row.ID
+		}
+		if first, dup := seen[key]; err == nil && dup {
+			err = fmt.Errorf("product already on line %d", first)
+		}
+		if err == nil {
+			seen[key] = row.Line
+		}
+		if err != nil {
+			rowErrs = append(rowErrs, models.ImportRowError{Line: row.Line, ID: row.ID, SKU: row.SKU, Error: err.Error()})
+		} else {
+			rows = append(rows, row)
+		}
+		if len(rows)+len(rowErrs) > MaxRows {
+			return fmt.Errorf("more than %d rows, split the file", MaxRows)
+		}
+		return nil
+	}
+
+	switch format {
+	case FormatCSV:
+		return rows, rowErrs, parseCSV(r, add)
+	case FormatNDJSON:
+		return rows, rowErrs, parseNDJSON(r, add)
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q, use csv or ndjson", format)
+	}
+}
+
+func parseCSV(r io.Reader, add func(Row, error) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading csv header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "price_cents"} {
+		if _, ok := index[required]; !ok {
+			return fmt.Errorf("csv header is missing %s", required)
+		}
+	}
+	_, hasID := index["id"]
+	if _, hasSKU := index["sku"]; !hasSKU && !hasID {
+		return errors.New("csv header is missing sku or id")
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := add(Row{Line: parseErr.StartLine}, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// quoted fields can span lines
+		line, _ := cr.FieldPos(0)
+
+		get := func(col string) string {
+			if i, ok := index[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := Row{
+			Line:        line,
+			ID:          get("id"),
+			SKU:         get("sku"),
+			Name:        get("name"),
+			Description: get("description"),
+			Category:    get("category"),
+			CategoryID:  get("category_id"),
+			Image:       get("image"),
+		}
+		var rowErr error
+		if row.PriceCents, err = strconv.ParseInt(get("price_cents"), 10, 64); err != nil {
+			rowErr = errors.New("price_cents must be a whole number of cents")
+		}
+		if v := get("stock"); v != "" && rowErr == nil {
+			stock, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				rowErr = errors.New("stock must be a whole number")
+			}
+			row.Stock = &stock
+		}
+		if err := add(row, rowErr); err != nil {
+			return err
+		}
+	}
+}
+
+func parseNDJSON(r io.Reader, add func(Row, error) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		row := Row{}
+		err := json.Unmarshal([]byte(text), &row)
+		row.Line = line
+		if err != nil {
+			err = fmt.Errorf("invalid json: %v", err)
+		}
+		if err := add(row, err); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, category *models.Category) error
+	GetCategory(ctx context.Context, id string) (*models.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error)
+	ListCategories(ctx context.Context) ([]*models.Category, error)
+	Descendants(ctx context.Context, id string) ([]*models.Category, error)
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, id string) error
+}
+
+var (
+	ErrSlugTaken        = errors.New("slug already in use")
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+type mongoCategoryRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoCategoryRepo(client *mongo.Client, dbName string) *mongoCategoryRepo {
+	col := client.Database(dbName).Collection("categories")
+
+	_, _ = col.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "position", Value: 1}}},
+	})
+
+	return &mongoCategoryRepo{col: col}
+}
+
+func (repo *mongoCategoryRepo) CreateCategory(ctx context.Context, category *models.Category) error {
+	category.ID = primitive.NewObjectID()
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	if category.Ancestors == nil {
+		category.Ancestors = []string{}
+	}
+
+	_, err := repo.col.InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSlugTaken
+	}
+	return err
+}
+
+func (repo *mongoCategoryRepo) findOne(ctx context.Context, filter bson.M) (*models.Category, error) {
+	var category models.Category
+	err := repo.col.FindOne(ctx, filter).Decode(&category)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (repo *mongoCategoryRepo) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid category id")
+	}
+	return repo.findOne(ctx, bson.M{"_id": objID})
+}
+
+func (repo *mongoCategoryRepo) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	return repo.findOne(ctx, bson.M{"slug": slug})
+}
+
+func (repo *mongoCategoryRepo) find(ctx context.Context, filter bson.M) ([]*models.Category, error) {
+	categories := []*models.Category{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+	res, err := repo.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var category models.Category
+		if err := res.Decode(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// ListCategories returns every category ordered by position then name.
+func (repo *mongoCategoryRepo) ListCategories(ctx context.Context) ([]*models.Category, error) {
+	return repo.find(ctx, bson.M{})
+}
+
+func (repo *mongoCategoryRepo) Descendants(ctx context.Context, id string) ([]*models.Category, error) {
+	return repo.find(ctx, bson.M{"ancestors": id})
+}
+
+func (repo *mongoCategoryRepo) UpdateCategory(ctx context.Context, category *models.Category) error {
+	category.UpdatedAt = time.Now()
+	res, err := repo.col.ReplaceOne(ctx, bson.M{"_id": category.ID}, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (repo *mongoCategoryRepo) DeleteCategory(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid category id")
+	}
+	res, err := repo.col.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type ImportJobRepository interface {
+	CreateJob(ctx context.Context, job *models.ImportJob) error
+	SaveJob(ctx context.Context, job *models.ImportJob) error
+	GetJob(ctx context.Context, id string) (*models.ImportJob, error)
+	// FailRunning fails the jobs a previous run of the service left behind
+	FailRunning(ctx context.Context, reason string) (int64, error)
+}
+
+var ErrInvalidJobID = errors.New("invalid job id")
+
+type mongoImportJobRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoImportJobRepo(client *mongo.Client, dbName string) *mongoImportJobRepo {
+	return &mongoImportJobRepo{col: client.Database(dbName).Collection("import_jobs")}
+}
+
+func (repo *mongoImportJobRepo) CreateJob(ctx context.Context, job *models.ImportJob) error {
+	job.ID = primitive.NewObjectID()
+	job.CreatedAt = time.Now()
+	_, err := repo.col.InsertOne(ctx, job)
+	return err
+}
+
+func (repo *mongoImportJobRepo) SaveJob(ctx context.Context, job *models.ImportJob) error {
+	_, err := repo.col.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	return err
+}
+
+func (repo *mongoImportJobRepo) GetJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidJobID
+	}
+	var job models.ImportJob
+	err = repo.col.FindOne(ctx, bson.M{"_id": objID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (repo *mongoImportJobRepo) FailRunning(ctx context.Context, reason string) (int64, error) {
+	res, err := repo.col.UpdateMany(ctx,
+		bson.M{"status": models.ImportRunning},
+		bson.M{"$set": bson.M{"status": models.ImportFailed, "error": reason, "finished_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
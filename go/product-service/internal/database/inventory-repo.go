@@ -0,0 +1,478 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type InventoryRepository interface {
+	AdjustStock(ctx context.Context, productID string, movement *models.StockMovement) (*models.Inventory, error)
+	ListMovements(ctx context.Context, productID string, limit int64) ([]*models.StockMovement, error)
+
+	// the reservation calls also return the inventory after the change for each
+	// line, nil for lines that weren't held
+	ReserveStock(ctx context.Context, orderID string, lines []models.ReservationLine, ttl time.Duration) (*models.Reservation, []*models.Inventory, error)
+	CommitReservation(ctx context.Context, orderID string) (*models.Reservation, []*models.Inventory, error)
+	ReleaseReservation(ctx context.Context, orderID string, status models.ReservationStatus, reason string) (*models.Reservation, []*models.Inventory, error)
+	ExpiredReservations(ctx context.Context, now time.Time, limit int64) ([]*models.Reservation, error)
+	// UnsettledReservations are commits and releases that stopped halfway, not
+	// touched since before
+	UnsettledReservations(ctx context.Context, before time.Time, limit int64) ([]*models.Reservation, error)
+}
+
+var (
+	ErrProductNotFound      = errors.New("product not found")
+	ErrInsufficientStock    = errors.New("not enough stock")
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation is no longer active")
+	ErrVariantNotFound      = errors.New("variant not found")
+	ErrVariantRequired      = errors.New("product has variants, pick one")
+	ErrStockContention      = errors.New("stock is changing too fast, retry")
+)
+
+// compare-and-swap retries before giving up on a busy product
+const stockCASAttempts = 5
+
+type mongoInventoryRepo struct {
+	products     *mongo.Collection
+	movements    *mongo.Collection
+	reservations *mongo.Collection
+}
+
+func NewMongoInventoryRepo(client *mongo.Client, dbName string) *mongoInventoryRepo {
+	db := client.Database(dbName)
+	movements := db.Collection("stock_movements")
+
+	idxModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+	_, _ = movements.Indexes().CreateOne(context.Background(), idxModel)
+
+	reservations := db.Collection("stock_reservations")
+	_, _ = reservations.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "order_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "settling", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+
+	return &mongoInventoryRepo{
+		products:     db.Collection("product"),
+		movements:    movements,
+		reservations: reservations,
+	}
+}
+
+// AdjustStock applies movement.Delta to on hand (of movement.VariantID if set)
+// and records the movement. On hand can't drop below what is reserved, that
+// stock is already promised.
+func (repo *mongoInventoryRepo) AdjustStock(ctx context.Context, productID string, movement *models.StockMovement) (*models.Inventory, error) {
+	stock, err := repo.changeStock(ctx, productID, movement.VariantID, movement.Delta, 0, func(cur models.Inventory) error {
+		if cur.OnHand+movement.Delta < cur.Reserved {
+			return ErrInsufficientStock
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	movement.ID = primitive.NewObjectID()
+	movement.ProductID = productID
+	movement.OnHand = stock.OnHand
+	movement.CreatedAt = time.Now()
+	if _, err := repo.movements.InsertOne(ctx, movement); err != nil {
+		return nil, err
+	}
+	return stock, nil
+}
+
+// changeStock adds onHand and reserved to the product's stock, or the variant's
+// when variantID is set. There is no $expr over array elements so it is a
+// compare-and-swap on the current values, check sees them before the change.
+func (repo *mongoInventoryRepo) changeStock(ctx context.Context, productID, variantID string, onHand, reserved int64, check func(models.Inventory) error) (*models.Inventory, error) {
+	objID, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	for attempt := 0; attempt < stockCASAttempts; attempt++ {
+		var product models.Product
+		err := repo.products.FindOne(ctx, bson.M{"_id": objID}).Decode(&product)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProductNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		// held stock of a deleted product can still be released or sold, new
+		// holds can't be placed
+		if reserved > 0 && product.DeletedAt != nil {
+			return nil, ErrProductNotFound
+		}
+
+		cur := product.Stock
+		prefix := "stock."
+		filter := bson.M{
+			"_id": objID,
+			// products from before inventory have no stock fields
+			"stock.on_hand":  orMissing(cur.OnHand),
+			"stock.reserved": orMissing(cur.Reserved),
+		}
+		switch {
+		case variantID != "":
+			variant := product.Variant(variantID)
+			if variant == nil {
+				return nil, ErrVariantNotFound
+			}
+			cur = variant.Stock
+			prefix = "variants.$.stock."
+			filter = bson.M{
+				"_id": objID,
+				"variants": bson.M{"$elemMatch": bson.M{
+					"id":             variantID,
+					"stock.on_hand":  cur.OnHand,
+					"stock.reserved": cur.Reserved,
+				}},
+			}
+		case len(product.Variants) > 0:
+			return nil, ErrVariantRequired
+		}
+
+		if check != nil {
+			if err := check(cur); err != nil {
+				return nil, err
+			}
+		}
+		res, err := repo.products.UpdateOne(ctx, filter, bson.M{
+			"$inc": bson.M{prefix + "on_hand": onHand, prefix + "reserved": reserved},
+			"$set": bson.M{"updated_at": time.Now()},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 1 {
+			cur.OnHand += onHand
+			cur.Reserved += reserved
+			return &cur, nil
+		}
+	}
+	return nil, ErrStockContention
+}
+
+func orMissing(v int64) any {
+	if v == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return v
+}
+
+func (repo *mongoInventoryRepo) ListMovements(ctx context.Context, productID string, limit int64) ([]*models.StockMovement, error) {
+	movements := []*models.StockMovement{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	res, err := repo.movements.Find(ctx, bson.M{"product_id": productID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var movement models.StockMovement
+		if err := res.Decode(&movement); err != nil {
+			return nil, err
+		}
+		movements = append(movements, &movement)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+func (repo *mongoInventoryRepo) getReservation(ctx context.Context, orderID string) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := repo.reservations.FindOne(ctx, bson.M{"order_id": orderID}).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// ReserveStock moves each line from available to reserved, all or nothing.
+// The record is written first and each line is flagged held right after its
+// stock is taken, so a crash halfway is given back by the sweeper on expiry.
+// A crash between a line's stock change and its flag is not: that hold is
+// missing from the record and stays reserved until corrected by hand.
+func (repo *mongoInventoryRepo) ReserveStock(ctx context.Context, orderID string, lines []models.ReservationLine, ttl time.Duration) (*models.Reservation, []*models.Inventory, error) {
+	existing, err := repo.getReservation(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		if existing.Status != models.ReservationActive {
+			return existing, nil, ErrReservationNotActive
+		}
+		return existing, nil, nil
+	}
+
+	now := time.Now()
+	reservation := &models.Reservation{
+		ID:        primitive.NewObjectID(),
+		OrderID:   orderID,
+		Lines:     lines,
+		Status:    models.ReservationActive,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := repo.reservations.InsertOne(ctx, reservation); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// same order reserved concurrently, the other call wins
+			existing, err := repo.getReservation(ctx, orderID)
+			if err != nil {
+				return nil, nil, err
+			}
+			return existing, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	levels := make([]*models.Inventory, len(reservation.Lines))
+	for i, line := range reservation.Lines {
+		stock, err := repo.changeStock(ctx, line.ProductID, line.VariantID, 0, line.Quantity, func(cur models.Inventory) error {
+			if cur.Available() < line.Quantity {
+				return ErrInsufficientStock
+			}
+			return nil
+		})
+		if err != nil {
+			repo.abortReservation(ctx, reservation, "reserve failed")
+			return nil, nil, fmt.Errorf("%w: %s", err, line.ProductID)
+		}
+		levels[i] = stock
+
+		reservation.Lines[i].Held = true
+		_, err = repo.reservations.UpdateOne(ctx,
+			bson.M{"_id": reservation.ID},
+			bson.M{"$set": bson.M{fmt.Sprintf("lines.%d.held", i): true}},
+		)
+		if err != nil {
+			repo.abortReservation(ctx, reservation, "reserve failed")
+			return nil, nil, err
+		}
+	}
+	return reservation, levels, nil
+}
+
+// abortReservation gives back what a failed ReserveStock already took. Errors
+// are ignored, a record still active is released by the sweeper on expiry and
+// one left settling is finished by its unsettled sweep.
+func (repo *mongoInventoryRepo) abortReservation(ctx context.Context, reservation *models.Reservation, reason string) {
+	_, _, _ = repo.ReleaseReservation(ctx, reservation.OrderID, models.ReservationReleased, reason)
+}
+
+// CommitReservation turns the reserved stock into a sale: on hand and reserved
+// both go down. The status flips first, so no release or expiry can take the
+// order anymore, then the lines are settled one by one. An error leaves the
+// record settling, calling again finishes the remaining lines and committing a
+// settled reservation is a no-op.
+func (repo *mongoInventoryRepo) CommitReservation(ctx context.Context, orderID string) (*models.Reservation, []*models.Inventory, error) {
+	reservation, err := repo.transition(ctx, orderID, models.ReservationCommitted, "")
+	if err != nil {
+		return reservation, nil, err
+	}
+	if reservation == nil {
+		existing, err := repo.getReservation(ctx, orderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing == nil {
+			return nil, nil, ErrReservationNotFound
+		}
+		if existing.Status != models.ReservationCommitted {
+			return existing, nil, ErrReservationNotActive
+		}
+		if !existing.Settling {
+			return existing, nil, nil
+		}
+		reservation = existing
+	}
+
+	levels, err := repo.settle(ctx, reservation)
+	if err != nil {
+		return reservation, nil, err
+	}
+	return reservation, levels, nil
+}
+
+// ReleaseReservation gives the reserved stock back, status is released or
+// expired. Like a commit it settles line by line after the status flip, so a
+// failed release is finished by calling again. Releasing twice is a no-op,
+// releasing a committed order fails.
+func (repo *mongoInventoryRepo) ReleaseReservation(ctx context.Context, orderID string, status models.ReservationStatus, reason string) (*models.Reservation, []*models.Inventory, error) {
+	reservation, err := repo.transition(ctx, orderID, status, reason)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reservation == nil {
+		existing, err := repo.getReservation(ctx, orderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing == nil {
+			return nil, nil, ErrReservationNotFound
+		}
+		if existing.Status == models.ReservationCommitted {
+			return existing, nil, ErrReservationNotActive
+		}
+		if !existing.Settling {
+			return existing, nil, nil
+		}
+		reservation = existing
+	}
+
+	levels, err := repo.settle(ctx, reservation)
+	if err != nil {
+		return reservation, nil, err
+	}
+	return reservation, levels, nil
+}
+
+// settle applies the held lines of a committed or released reservation to
+// stock, flagging each line settled right after, and clears Settling last.
+// A crash between a line's stock change and its flag applies that line again
+// on the retry, the window is one write wide.
+func (repo *mongoInventoryRepo) settle(ctx context.Context, reservation *models.Reservation) ([]*models.Inventory, error) {
+	sold := reservation.Status == models.ReservationCommitted
+	levels := make([]*models.Inventory, len(reservation.Lines))
+	for i, line := range reservation.Lines {
+		if !line.Held || line.Settled {
+			continue
+		}
+		var onHand int64
+		if sold {
+			onHand = -line.Quantity
+		}
+		inventory, err := repo.changeStock(ctx, line.ProductID, line.VariantID, onHand, -line.Quantity, nil)
+		if err != nil {
+			return nil, err
+		}
+		levels[i] = inventory
+
+		if sold {
+			movement := &models.StockMovement{
+				ID:        primitive.NewObjectID(),
+				ProductID: line.ProductID,
+				VariantID: line.VariantID,
+				Delta:     -line.Quantity,
+				Reason:    models.StockSold,
+				Note:      "order " + reservation.OrderID,
+				ActorID:   "checkout",
+				OnHand:    inventory.OnHand,
+				CreatedAt: time.Now(),
+			}
+			if _, err := repo.movements.InsertOne(ctx, movement); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = repo.reservations.UpdateOne(ctx,
+			bson.M{"_id": reservation.ID},
+			bson.M{"$set": bson.M{fmt.Sprintf("lines.%d.settled", i): true, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservation.Lines[i].Settled = true
+	}
+
+	_, err := repo.reservations.UpdateOne(ctx,
+		bson.M{"_id": reservation.ID},
+		bson.M{"$unset": bson.M{"settling": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	reservation.Settling = false
+	return levels, nil
+}
+
+// transition moves an active reservation to status and marks it settling, nil
+// if it wasn't active.
+func (repo *mongoInventoryRepo) transition(ctx context.Context, orderID string, status models.ReservationStatus, reason string) (*models.Reservation, error) {
+	set := bson.M{"status": status, "settling": true, "updated_at": time.Now()}
+	if reason != "" {
+		set["reason"] = reason
+	}
+	var reservation models.Reservation
+	err := repo.reservations.FindOneAndUpdate(ctx,
+		bson.M{"order_id": orderID, "status": models.ReservationActive},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (repo *mongoInventoryRepo) ExpiredReservations(ctx context.Context, now time.Time, limit int64) ([]*models.Reservation, error) {
+	reservations := []*models.Reservation{}
+
+	filter := bson.M{"status": models.ReservationActive, "expires_at": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	res, err := repo.reservations.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var reservation models.Reservation
+		if err := res.Decode(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &reservation)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (repo *mongoInventoryRepo) UnsettledReservations(ctx context.Context, before time.Time, limit int64) ([]*models.Reservation, error) {
+	reservations := []*models.Reservation{}
+
+	filter := bson.M{"settling": true, "updated_at": bson.M{"$lte": before}}
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	res, err := repo.reservations.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	if err := res.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
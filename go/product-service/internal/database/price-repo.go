@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type PriceRepository interface {
+	RecordPrice(ctx context.Context, record *models.PriceRecord) error
+	ListHistory(ctx context.Context, productID string, limit int64) ([]*models.PriceRecord, error)
+
+	// CreateSchedule fails with ErrSaleOverlaps for a sale overlapping another
+	// pending or running one of the product
+	CreateSchedule(ctx context.Context, schedule *models.PriceSchedule) error
+	ListSchedules(ctx context.Context, productID string) ([]*models.PriceSchedule, error)
+	GetSchedule(ctx context.Context, productID, id string) (*models.PriceSchedule, error)
+	// SetScheduleStatus moves a schedule on, only if it still has status from
+	SetScheduleStatus(ctx context.Context, id primitive.ObjectID, from, to models.ScheduleStatus, errMsg string) (bool, error)
+	// ClaimDue hands out the next schedule whose start or sale end is due,
+	// moving it to applying so no other instance picks it up, along with the
+	// status it gets once applied. Claims older than timeout are handed out
+	// again, their instance died before finishing. The returned schedule has
+	// the status from before the claim. nil when nothing is due.
+	ClaimDue(ctx context.Context, now time.Time, timeout time.Duration) (*models.PriceSchedule, models.ScheduleStatus, error)
+}
+
+var (
+	ErrScheduleNotFound = errors.New("price schedule not found")
+	ErrSaleOverlaps     = errors.New("sale overlaps another sale of the product")
+)
+
+type mongoPriceRepo struct {
+	history   *mongo.Collection
+	schedules *mongo.Collection
+}
+
+func NewMongoPriceRepo(client *mongo.Client, dbName string) *mongoPriceRepo {
+	db := client.Database(dbName)
+	history := db.Collection("price_history")
+	_, _ = history.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	schedules := db.Collection("price_schedules")
+	_, _ = schedules.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "ends_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "claimed_at", Value: 1}}},
+	})
+	return &mongoPriceRepo{history: history, schedules: schedules}
+}
+
+func (repo *mongoPriceRepo) RecordPrice(ctx context.Context, record *models.PriceRecord) error {
+	record.ID = primitive.NewObjectID()
+	record.CreatedAt = time.Now()
+	_, err := repo.history.InsertOne(ctx, record)
+	return err
+}
+
+func (repo *mongoPriceRepo) ListHistory(ctx context.Context, productID string, limit int64) ([]*models.PriceRecord, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	res, err := repo.history.Find(ctx, bson.M{"product_id": productID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	records := []*models.PriceRecord{}
+	for res.Next(ctx) {
+		var record models.PriceRecord
+		if err := res.Decode(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, res.Err()
+}
+
+func (repo *mongoPriceRepo) CreateSchedule(ctx context.Context, schedule *models.PriceSchedule) error {
+	// racing admins could still both get an overlapping sale in, the scheduler
+	// copes by keeping the regular price from before the first one
+	if schedule.Kind == models.ScheduleSale {
+		n, err := repo.schedules.CountDocuments(ctx, bson.M{
+			"product_id": schedule.ProductID,
+			"kind":       models.ScheduleSale,
+			"status":     bson.M{"$in": bson.A{models.SchedulePending, models.ScheduleActive, models.ScheduleApplying}},
+			"starts_at":  bson.M{"$lt": schedule.EndsAt},
+			"ends_at":    bson.M{"$gt": schedule.StartsAt},
+		})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrSaleOverlaps
+		}
+	}
+
+	now := time.Now()
+	schedule.ID = primitive.NewObjectID()
+	schedule.Status = models.SchedulePending
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	_, err := repo.schedules.InsertOne(ctx, schedule)
+	return err
+}
+
+func (repo *mongoPriceRepo) ListSchedules(ctx context.Context, productID string) ([]*models.PriceSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "starts_at", Value: -1}})
+	res, err := repo.schedules.Find(ctx, bson.M{"product_id": productID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	schedules := []*models.PriceSchedule{}
+	for res.Next(ctx) {
+		var schedule models.PriceSchedule
+		if err := res.Decode(&schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+	return schedules, res.Err()
+}
+
+func (repo *mongoPriceRepo) GetSchedule(ctx context.Context, productID, id string) (*models.PriceSchedule, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrScheduleNotFound
+	}
+	var schedule models.PriceSchedule
+	err = repo.schedules.FindOne(ctx, bson.M{"_id": objID, "product_id": productID}).Decode(&schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (repo *mongoPriceRepo) SetScheduleStatus(ctx context.Context, id primitive.ObjectID, from, to models.ScheduleStatus, errMsg string) (bool, error) {
+	set := bson.M{"status": to, "updated_at": time.Now()}
+	if errMsg != "" {
+		set["error"] = errMsg
+	}
+	update := bson.M{"$set": set, "$unset": bson.M{"claimed_at": "", "claimed_from": ""}}
+	res, err := repo.schedules.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (repo *mongoPriceRepo) ClaimDue(ctx context.Context, now time.Time, timeout time.Duration) (*models.PriceSchedule, models.ScheduleStatus, error) {
+	// abandoned claims first, they have been due the longest
+	var stale models.PriceSchedule
+	err := repo.schedules.FindOneAndUpdate(ctx,
+		bson.M{"status": models.ScheduleApplying, "claimed_at": bson.M{"$lte": now.Add(-timeout)}},
+		bson.M{"$set": bson.M{"claimed_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "claimed_at", Value: 1}}),
+	).Decode(&stale)
+	if err == nil {
+		stale.Status = stale.ClaimedFrom
+		return &stale, appliedStatus(&stale), nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", err
+	}
+
+	claims := []struct {
+		filter bson.M
+		sort   string
+	}{
+		// sales ending go first, a sale starting right after finds the regular price back
+		{bson.M{"status": models.ScheduleActive, "ends_at": bson.M{"$lte": now}}, "ends_at"},
+		{bson.M{"status": models.SchedulePending, "kind": models.SchedulePrice, "starts_at": bson.M{"$lte": now}}, "starts_at"},
+		{bson.M{"status": models.SchedulePending, "kind": models.ScheduleSale, "starts_at": bson.M{"$lte": now}}, "starts_at"},
+	}
+	for _, c := range claims {
+		var schedule models.PriceSchedule
+		err := repo.schedules.FindOneAndUpdate(ctx, c.filter,
+			bson.A{bson.M{"$set": bson.M{
+				"status":       models.ScheduleApplying,
+				"claimed_from": "$status",
+				"claimed_at":   now,
+				"updated_at":   now,
+			}}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: c.sort, Value: 1}}),
+		).Decode(&schedule)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		// the document from before the claim, its status says what to do
+		return &schedule, appliedStatus(&schedule), nil
+	}
+	return nil, "", nil
+}
+
+// appliedStatus is where a schedule goes once its due change is made: a
+// starting sale runs until its end, everything else is done.
+func appliedStatus(schedule *models.PriceSchedule) models.ScheduleStatus {
+	if schedule.Status == models.SchedulePending && schedule.Kind == models.ScheduleSale {
+		return models.ScheduleActive
+	}
+	return models.ScheduleDone
+}
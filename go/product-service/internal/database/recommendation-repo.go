@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type RecommendationRepository interface {
+	// BoughtTogether is nil for a product without co-purchases
+	BoughtTogether(ctx context.Context, productID string) (*models.BoughtTogether, error)
+	// ComputeBoughtTogether rebuilds every list from the orders paid since
+	// since, keeping pairs seen in at least minOrders orders and the top
+	// perProduct of them. Returns how many products got a list.
+	ComputeBoughtTogether(ctx context.Context, since time.Time, minOrders, perProduct int64) (int64, error)
+}
+
+type mongoRecommendationRepo struct {
+	reservations   *mongo.Collection
+	boughtTogether *mongo.Collection
+}
+
+func NewMongoRecommendationRepo(client *mongo.Client, dbName string) *mongoRecommendationRepo {
+	db := client.Database(dbName)
+	reservations := db.Collection("stock_reservations")
+	// the paid orders of the window
+	_, _ = reservations.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return &mongoRecommendationRepo{
+		reservations:   reservations,
+		boughtTogether: db.Collection("bought_together"),
+	}
+}
+
+func (repo *mongoRecommendationRepo) BoughtTogether(ctx context.Context, productID string) (*models.BoughtTogether, error) {
+	var list models.BoughtTogether
+	err := repo.boughtTogether.FindOne(ctx, bson.M{"_id": productID}).Decode(&list)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ComputeBoughtTogether runs entirely in mongo: every committed reservation is
+// an order, its distinct products are paired up and the pairs counted. Lists
+// are replaced in place so reads never see an empty collection, the ones not
+// rebuilt by this run are dropped after.
+func (repo *mongoRecommendationRepo) ComputeBoughtTogether(ctx context.Context, since time.Time, minOrders, perProduct int64) (int64, error) {
+	// mongo keeps milliseconds, the cleanup below compares against the stored value
+	runAt := time.Now().Truncate(time.Millisecond)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":     models.ReservationCommitted,
+			"created_at": bson.M{"$gte": since},
+		}}},
+		// a product on two lines (two variants) is still one order
+		{{Key: "$project", Value: bson.M{"_id": 0, "products": bson.M{"$setUnion": bson.A{"$lines.product_id"}}}}},
+		{{Key: "$project", Value: bson.M{"products": 1, "other": "$products"}}},
+		{{Key: "$unwind", Value: "$products"}},
+		{{Key: "$unwind", Value: "$other"}},
+		// pairing a product with itself counts its orders
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"product": "$products", "other": "$other"},
+			"orders": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.product", Value: 1}, {Key: "orders", Value: -1}, {Key: "_id.other", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$_id.product",
+			"orders": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$_id.product", "$_id.other"}}, "$orders", 0,
+			}}},
+			"products": bson.M{"$push": bson.M{"product_id": "$_id.other", "orders": "$orders"}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"orders": 1,
+			"products": bson.M{"$filter": bson.M{
+				"input": "$products",
+				"cond": bson.M{"$and": bson.A{
+					bson.M{"$ne": bson.A{"$$this.product_id", "$_id"}},
+					bson.M{"$gte": bson.A{"$$this.orders", minOrders}},
+				}},
+			}},
+		}}},
+		{{Key: "$match", Value: bson.M{"products.0": bson.M{"$exists": true}}}},
+		{{Key: "$project", Value: bson.M{
+			"orders":      1,
+			"products":    bson.M{"$slice": bson.A{"$products", perProduct}},
+			"computed_at": runAt,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           repo.boughtTogether.Name(),
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	}
+	cur, err := repo.reservations.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	cur.Close(ctx)
+
+	if _, err := repo.boughtTogether.DeleteMany(ctx, bson.M{"computed_at": bson.M{"$lt": runAt}}); err != nil {
+		return 0, err
+	}
+	return repo.boughtTogether.CountDocuments(ctx, bson.M{})
+}
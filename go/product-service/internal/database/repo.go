@@ -2,6 +2,8 @@ package database
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"product-service/internal/models"
@@ -17,9 +19,88 @@ type ProductRepository interface {
 	GetAllProducts(ctx context.Context) ([]*models.Product, error)
 	CreateProduct(ctx context.Context, product *models.Product) (*models.Product, error)
 	GetProductById(ctx context.Context, id string) (*models.Product, error)
-	UpdateProduct(ctx context.Context, id string, changes map[string]interface{}) (*models.Product, error)
-	DeleteProduct(ctx context.Context, id string) error
+	// GetProductsByIds returns the products found in no particular order,
+	// deleted ones included. Invalid ids are skipped.
+	GetProductsByIds(ctx context.Context, ids []string) ([]*models.Product, error)
+	// UpdateProduct sets changes and bumps the version, with expectedVersion
+	// only if the product is still at it
+	UpdateProduct(ctx context.Context, id string, changes map[string]interface{}, expectedVersion *int64) (*models.Product, error)
+	// DeleteProduct is a soft delete, it returns the product with DeletedAt set
+	DeleteProduct(ctx context.Context, id string) (*models.Product, error)
 	SearchProduct(ctx context.Context, filter bson.M, limit, skip int64) ([]*models.Product, error)
+	ListProducts(ctx context.Context, filter ProductFilter) ([]*models.Product, string, int64, error)
+	// SetVariants replaces options and variants, only if the product is still
+	// at version, ErrProductChanged otherwise
+	SetVariants(ctx context.Context, id string, options []models.ProductOption, variants []models.Variant, version int64) (*models.Product, error)
+	// RenameCategory updates the denormalized category name on its products
+	RenameCategory(ctx context.Context, categoryID, name string) (int64, error)
+	CountByCategory(ctx context.Context, categoryID string) (int64, error)
+	// GetProductBySKU matches the product sku or a variant sku, nil if neither
+	// exists. Deleted products still hold their skus.
+	GetProductBySKU(ctx context.Context, sku string) (*models.Product, error)
+	// BackfillRatings gives products from before ratings existed a zero
+	// rating, the keyset cursor of the rating sort skips a missing one
+	BackfillRatings(ctx context.Context) (int64, error)
+	// EachProduct streams the whole catalog in id order, without deleted products
+	EachProduct(ctx context.Context, fn func(*models.Product) error) error
+	// SetGallery replaces the gallery and mirrors its primary onto image/images,
+	// only if the product is still at version, ErrProductChanged otherwise
+	SetGallery(ctx context.Context, id string, gallery []models.GalleryImage, version int64) (*models.Product, error)
+	// SetRating stores the review aggregate, it leaves updated_at alone
+	SetRating(ctx context.Context, id string, rating models.RatingSummary) error
+}
+
+// sortable fields for listings, keyed by the query param value
+var sortFields = map[string]string{
+	"price":      "price",
+	"created_at": "created_at",
+	"name":       "name",
+	"rating":     "rating.average",
+}
+
+// ProductFilter is for the paginated listings, zero values mean no filter.
+type ProductFilter struct {
+	Match    bson.M // extra conditions, e.g. the search query
+	Category string
+	MinPrice *int64
+	MaxPrice *int64
+	Sort     string // one of sortFields, defaults to created_at
+	Desc     bool
+	Cursor   string // next_cursor of the previous page
+	Limit    int64
+}
+
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrProductChanged = errors.New("product changed since it was read")
+	ErrSKUTaken       = errors.New("sku already in use")
+	// the product was edited since the caller read it
+	ErrVersionMismatch = errors.New("product version changed")
+)
+
+// notDeleted matches the products listings should show.
+var notDeleted = bson.M{"$exists": false}
+
+// Query is the mongo filter for everything but the cursor.
+func (f ProductFilter) Query() bson.M {
+	query := bson.M{"deleted_at": notDeleted}
+	for k, v := range f.Match {
+		query[k] = v
+	}
+	if f.Category != "" {
+		query["category"] = f.Category
+	}
+	price := bson.M{}
+	if f.MinPrice != nil {
+		price["$gte"] = *f.MinPrice
+	}
+	if f.MaxPrice != nil {
+		price["$lte"] = *f.MaxPrice
+	}
+	if len(price) > 0 {
+		query["price"] = price
+	}
+	return query
 }
 
 type mongoProductRepo struct {
@@ -28,12 +109,32 @@ type mongoProductRepo struct {
 
 func NewMongoRepo(client *mongo.Client, dbName string) *mongoProductRepo {
 	col := client.Database(dbName).Collection("product")
+
+	// one index per sort so the listing pages don't scan
+	var idxModels []mongo.IndexModel
+	for _, field := range sortFields {
+		idxModels = append(idxModels, mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}})
+	}
+	idxModels = append(idxModels,
+		mongo.IndexModel{Keys: bson.D{{Key: "category", Value: 1}, {Key: "price", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "category_id", Value: 1}}},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "sku", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	)
+	_, _ = col.Indexes().CreateMany(context.Background(), idxModels)
+
 	return &mongoProductRepo{col: col}
 }
 func (repo *mongoProductRepo) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
 	var products []*models.Product
 
-	res, err := repo.col.Find(ctx, bson.M{})
+	res, err := repo.col.Find(ctx, bson.M{"deleted_at": notDeleted})
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +163,12 @@ func (repo *mongoProductRepo) CreateProduct(ctx context.Context, product *models
 	now := time.Now()
 	product.CreatedAt = now
 	product.UpdatedAt = now
+	product.SearchNgrams = models.SearchNgrams(product.Name, product.Category)
 
 	_, err := repo.col.InsertOne(ctx, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrSKUTaken
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -84,42 +189,95 @@ func (repo *mongoProductRepo) GetProductById(ctx context.Context, id string) (*m
 	}
 	return &product, nil
 }
-func (repo *mongoProductRepo) UpdateProduct(ctx context.Context, id string, changes map[string]interface{}) (*models.Product, error) {
+func (repo *mongoProductRepo) GetProductsByIds(ctx context.Context, ids []string) ([]*models.Product, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	products := []*models.Product{}
+	if len(objIDs) == 0 {
+		return products, nil
+	}
+	res, err := repo.col.Find(ctx, bson.M{"_id": bson.M{"$in": objIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var product models.Product
+		if err := res.Decode(&product); err != nil {
+			return nil, err
+		}
+		products = append(products, &product)
+	}
+	return products, res.Err()
+}
+func (repo *mongoProductRepo) UpdateProduct(ctx context.Context, id string, changes map[string]interface{}, expectedVersion *int64) (*models.Product, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid product id")
+		return nil, ErrProductNotFound
+	}
+	filter := bson.M{"_id": objID, "deleted_at": notDeleted}
+	if expectedVersion != nil {
+		// products from before versions have none, that is version 0
+		filter["version"] = orMissing(*expectedVersion)
 	}
 	changes["updated_at"] = time.Now()
-	update := bson.M{"$set": changes}
+	update := bson.M{"$set": changes, "$inc": bson.M{"version": 1}}
 
-	result, err := repo.col.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	var updatedProduct models.Product
+	err = repo.col.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updatedProduct)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if expectedVersion != nil {
+			n, err := repo.col.CountDocuments(ctx, bson.M{"_id": objID, "deleted_at": notDeleted})
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				return nil, ErrVersionMismatch
+			}
+		}
+		return nil, ErrProductNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	if result.MatchedCount == 0 {
-		return nil, errors.New("product not found")
-	}
 
-	var updatedProduct models.Product
-	if err := repo.col.FindOne(ctx, bson.M{"_id": objID}).Decode(&updatedProduct); err != nil {
-		return nil, err
+	_, nameChanged := changes["name"]
+	_, categoryChanged := changes["category"]
+	if nameChanged || categoryChanged {
+		updatedProduct.SearchNgrams = models.SearchNgrams(updatedProduct.Name, updatedProduct.Category)
+		_, err := repo.col.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"search_ngrams": updatedProduct.SearchNgrams}})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &updatedProduct, nil
 }
-func (repo *mongoProductRepo) DeleteProduct(ctx context.Context, id string) error {
+func (repo *mongoProductRepo) DeleteProduct(ctx context.Context, id string) (*models.Product, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid product id")
+		return nil, ErrProductNotFound
 	}
-	res, err := repo.col.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
-		return err
+	now := time.Now()
+	var product models.Product
+	err = repo.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": objID, "deleted_at": notDeleted},
+		bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrProductNotFound
 	}
-	if res.DeletedCount == 0 {
-		return errors.New("product not found")
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &product, nil
 }
 
 func (repo *mongoProductRepo) SearchProduct(ctx context.Context, filter bson.M, limit, skip int64) ([]*models.Product, error) {
@@ -132,6 +290,7 @@ func (repo *mongoProductRepo) SearchProduct(ctx context.Context, filter bson.M,
 	if skip > 0 {
 		findOpts.SetSkip(skip)
 	}
+	filter = bson.M{"$and": []bson.M{filter, {"deleted_at": notDeleted}}}
 	res, err := repo.col.Find(ctx, filter, findOpts)
 	if err != nil {
 		return nil, err
@@ -149,3 +308,290 @@ func (repo *mongoProductRepo) SearchProduct(ctx context.Context, filter bson.M,
 	}
 	return products, err
 }
+
+// listCursor is where the previous page stopped: the sort value and id of its
+// last product, the id breaks ties between equal values.
+type listCursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	ID        string    `json:"id"`
+	Price     int64     `json:"p,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Rating    float64   `json:"r,omitempty"`
+}
+
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func cursorFor(sort string, desc bool, p *models.Product) listCursor {
+	c := listCursor{Sort: sort, Desc: desc, ID: p.ID.Hex()}
+	switch sort {
+	case "price":
+		c.Price = p.PriceCents
+	case "name":
+		c.Name = p.Name
+	case "rating":
+		c.Rating = p.Rating.Average
+	default:
+		c.CreatedAt = p.CreatedAt
+	}
+	return c
+}
+
+func (c *listCursor) value() any {
+	switch c.Sort {
+	case "price":
+		return c.Price
+	case "name":
+		return c.Name
+	case "rating":
+		return c.Rating
+	default:
+		return c.CreatedAt
+	}
+}
+
+// ListProducts returns one page, the cursor for the next one ("" on the last
+// page) and the total matching the filter regardless of the cursor.
+func (repo *mongoProductRepo) ListProducts(ctx context.Context, filter ProductFilter) ([]*models.Product, string, int64, error) {
+	products := []*models.Product{}
+
+	if filter.Sort == "" {
+		filter.Sort = "created_at"
+	}
+	field, ok := sortFields[filter.Sort]
+	if !ok {
+		return nil, "", 0, fmt.Errorf("can't sort by %q", filter.Sort)
+	}
+
+	query := filter.Query()
+	total, err := repo.col.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	order := 1
+	cmp := "$gt"
+	if filter.Desc {
+		order = -1
+		cmp = "$lt"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		lastID, err := primitive.ObjectIDFromHex(c.ID)
+		if err != nil || c.Sort != filter.Sort || c.Desc != filter.Desc {
+			return nil, "", 0, ErrInvalidCursor
+		}
+		after := bson.M{"$or": []bson.M{
+			{field: bson.M{cmp: c.value()}},
+			{field: c.value(), "_id": bson.M{cmp: lastID}},
+		}}
+		query = bson.M{"$and": []bson.M{query, after}}
+	}
+
+	// one extra to know if there is a next page
+	opts := options.Find().SetSort(bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit + 1)
+	}
+	res, err := repo.col.Find(ctx, query, opts)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var product models.Product
+		if err := res.Decode(&product); err != nil {
+			return nil, "", 0, err
+		}
+		products = append(products, &product)
+	}
+	if err := res.Err(); err != nil {
+		return nil, "", 0, err
+	}
+
+	next := ""
+	if filter.Limit > 0 && int64(len(products)) > filter.Limit {
+		products = products[:filter.Limit]
+		next = encodeCursor(cursorFor(filter.Sort, filter.Desc, products[len(products)-1]))
+	}
+	return products, next, total, nil
+}
+
+func (repo *mongoProductRepo) SetVariants(ctx context.Context, id string, opts []models.ProductOption, variants []models.Variant, version int64) (*models.Product, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid product id")
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if len(variants) > 0 {
+		set["options"] = opts
+		set["variants"] = variants
+	} else {
+		unset["options"] = ""
+		unset["variants"] = ""
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	filter := bson.M{"_id": objID, "version": orMissing(version), "deleted_at": notDeleted}
+	if len(variants) > 0 {
+		// stock held on the product itself can't move once there are
+		// variants, only a product with none of its own gets its first ones
+		filter["$or"] = bson.A{
+			bson.M{"variants.0": bson.M{"$exists": true}},
+			bson.M{"stock.on_hand": orMissing(0), "stock.reserved": orMissing(0)},
+		}
+	}
+
+	var product models.Product
+	err = repo.col.FindOneAndUpdate(ctx, filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrSKUTaken
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrProductChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (repo *mongoProductRepo) GetProductBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	var product models.Product
+	err := repo.col.FindOne(ctx, bson.M{"$or": []bson.M{{"sku": sku}, {"variants.sku": sku}}}).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (repo *mongoProductRepo) EachProduct(ctx context.Context, fn func(*models.Product) error) error {
+	res, err := repo.col.Find(ctx, bson.M{"deleted_at": notDeleted}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var product models.Product
+		if err := res.Decode(&product); err != nil {
+			return err
+		}
+		if err := fn(&product); err != nil {
+			return err
+		}
+	}
+	return res.Err()
+}
+
+func (repo *mongoProductRepo) SetGallery(ctx context.Context, id string, gallery []models.GalleryImage, version int64) (*models.Product, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid product id")
+	}
+
+	set := bson.M{"gallery": gallery, "image": "", "images": nil, "updated_at": time.Now()}
+	for _, img := range gallery {
+		if img.Primary {
+			set["image"] = img.URL
+			set["images"] = img.Images
+		}
+	}
+
+	var product models.Product
+	err = repo.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": objID, "version": orMissing(version), "deleted_at": notDeleted},
+		bson.M{"$set": set, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrProductChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (repo *mongoProductRepo) RenameCategory(ctx context.Context, categoryID, name string) (int64, error) {
+	res, err := repo.col.Find(ctx, bson.M{"category_id": categoryID})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close(ctx)
+
+	var n int64
+	for res.Next(ctx) {
+		var product models.Product
+		if err := res.Decode(&product); err != nil {
+			return n, err
+		}
+		// the name feeds the search ngrams too
+		_, err := repo.col.UpdateOne(ctx, bson.M{"_id": product.ID}, bson.M{"$set": bson.M{
+			"category":      name,
+			"search_ngrams": models.SearchNgrams(product.Name, name),
+			"updated_at":    time.Now(),
+		}, "$inc": bson.M{"version": 1}})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, res.Err()
+}
+
+func (repo *mongoProductRepo) CountByCategory(ctx context.Context, categoryID string) (int64, error) {
+	return repo.col.CountDocuments(ctx, bson.M{"category_id": categoryID, "deleted_at": notDeleted})
+}
+
+func (repo *mongoProductRepo) BackfillRatings(ctx context.Context) (int64, error) {
+	res, err := repo.col.UpdateMany(ctx,
+		bson.M{"rating.average": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"rating": bson.M{
+			"average": 0.0,
+			"count":   bson.M{"$ifNull": bson.A{"$rating.count", 0}},
+		}}}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (repo *mongoProductRepo) SetRating(ctx context.Context, id string, rating models.RatingSummary) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid product id")
+	}
+	_, err = repo.col.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"rating": rating}})
+	return err
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type ReviewRepository interface {
+	CreateReview(ctx context.Context, review *models.Review) error
+	GetReview(ctx context.Context, id string) (*models.Review, error)
+	GetUserReview(ctx context.Context, productID, userID string) (*models.Review, error)
+	UpdateReview(ctx context.Context, review *models.Review) error
+	DeleteReview(ctx context.Context, id string) error
+	// ListApproved pages newest first, returns the cursor of the next page
+	ListApproved(ctx context.Context, productID, cursor string, limit int64) ([]*models.Review, string, error)
+	ListByStatus(ctx context.Context, status models.ReviewStatus, limit int64) ([]*models.Review, error)
+	Summary(ctx context.Context, productID string) (models.RatingSummary, error)
+}
+
+var ErrAlreadyReviewed = errors.New("you already reviewed this product")
+
+type mongoReviewRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoReviewRepo(client *mongo.Client, dbName string) *mongoReviewRepo {
+	col := client.Database(dbName).Collection("reviews")
+
+	_, _ = col.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		// one review per user per product
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+
+	return &mongoReviewRepo{col: col}
+}
+
+func (repo *mongoReviewRepo) CreateReview(ctx context.Context, review *models.Review) error {
+	review.ID = primitive.NewObjectID()
+	now := time.Now()
+	review.CreatedAt = now
+	review.UpdatedAt = now
+
+	_, err := repo.col.InsertOne(ctx, review)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyReviewed
+	}
+	return err
+}
+
+func (repo *mongoReviewRepo) findOne(ctx context.Context, filter bson.M) (*models.Review, error) {
+	var review models.Review
+	err := repo.col.FindOne(ctx, filter).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (repo *mongoReviewRepo) GetReview(ctx context.Context, id string) (*models.Review, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid review id")
+	}
+	return repo.findOne(ctx, bson.M{"_id": objID})
+}
+
+func (repo *mongoReviewRepo) GetUserReview(ctx context.Context, productID, userID string) (*models.Review, error) {
+	return repo.findOne(ctx, bson.M{"product_id": productID, "user_id": userID})
+}
+
+func (repo *mongoReviewRepo) UpdateReview(ctx context.Context, review *models.Review) error {
+	review.UpdatedAt = time.Now()
+	_, err := repo.col.ReplaceOne(ctx, bson.M{"_id": review.ID}, review)
+	return err
+}
+
+func (repo *mongoReviewRepo) DeleteReview(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid review id")
+	}
+	_, err = repo.col.DeleteOne(ctx, bson.M{"_id": objID})
+	return err
+}
+
+type reviewCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"id"`
+}
+
+func (repo *mongoReviewRepo) ListApproved(ctx context.Context, productID, cursor string, limit int64) ([]*models.Review, string, error) {
+	filter := bson.M{"product_id": productID, "status": models.ReviewApproved}
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		var c reviewCursor
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		lastID, err := primitive.ObjectIDFromHex(c.ID)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		filter["$or"] = []bson.M{
+			{"created_at": bson.M{"$lt": c.CreatedAt}},
+			{"created_at": c.CreatedAt, "_id": bson.M{"$lt": lastID}},
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit + 1)
+	reviews, err := repo.find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if int64(len(reviews)) > limit {
+		reviews = reviews[:limit]
+		last := reviews[len(reviews)-1]
+		data, _ := json.Marshal(reviewCursor{CreatedAt: last.CreatedAt, ID: last.ID.Hex()})
+		next = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return reviews, next, nil
+}
+
+// ListByStatus is the moderation queue, oldest first.
+func (repo *mongoReviewRepo) ListByStatus(ctx context.Context, status models.ReviewStatus, limit int64) ([]*models.Review, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+	return repo.find(ctx, bson.M{"status": status}, opts)
+}
+
+func (repo *mongoReviewRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.Review, error) {
+	reviews := []*models.Review{}
+
+	res, err := repo.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+	for res.Next(ctx) {
+		var review models.Review
+		if err := res.Decode(&review); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, &review)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func (repo *mongoReviewRepo) Summary(ctx context.Context, productID string) (models.RatingSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"product_id": productID, "status": models.ReviewApproved}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"average": bson.M{"$avg": "$rating"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+	}
+	cur, err := repo.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.RatingSummary{}, err
+	}
+	defer cur.Close(ctx)
+
+	var summary models.RatingSummary
+	if cur.Next(ctx) {
+		if err := cur.Decode(&summary); err != nil {
+			return models.RatingSummary{}, err
+		}
+	}
+	return summary, cur.Err()
+}
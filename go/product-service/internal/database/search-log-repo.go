@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// searches are counted per query and day, older days expire
+const searchLogRetention = 90 * 24 * time.Hour
+
+type SearchLogRepository interface {
+	// LogQuery counts one search for the normalized query
+	LogQuery(ctx context.Context, query string) error
+	// PopularQueries are the most run queries since since, most run first
+	PopularQueries(ctx context.Context, since time.Time, limit int64) ([]models.QueryCount, error)
+}
+
+type mongoSearchLogRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoSearchLogRepo(client *mongo.Client, dbName string) *mongoSearchLogRepo {
+	col := client.Database(dbName).Collection("search_queries")
+
+	_, _ = col.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "query", Value: 1}, {Key: "day", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "day", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(searchLogRetention.Seconds()))},
+	})
+
+	return &mongoSearchLogRepo{col: col}
+}
+
+func (repo *mongoSearchLogRepo) LogQuery(ctx context.Context, query string) error {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	_, err := repo.col.UpdateOne(ctx,
+		bson.M{"query": query, "day": day},
+		bson.M{"$inc": bson.M{"count": 1}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func (repo *mongoSearchLogRepo) PopularQueries(ctx context.Context, since time.Time, limit int64) ([]models.QueryCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$query", "count": bson.M{"$sum": "$count"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cur, err := repo.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	counts := []models.QueryCount{}
+	if err := cur.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
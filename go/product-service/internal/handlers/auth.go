@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"grpc_module/auth/authpb"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const adminRole = "admin"
+
+// authUser validates the auth cookie, returns the token claims.
+func (h *ProductHandler) authUser(w http.ResponseWriter, r *http.Request) (*authpb.ValidateTokenResponse, bool) {
+	cookie, err := r.Cookie("Authorization")
+	if err != nil {
+		h.logger.Warn("missing authorization cookie", zap.String("path", r.URL.Path))
+		http.Error(w, "missing auth cookie", http.StatusUnauthorized)
+		return nil, false
+	}
+	authResp, err := h.authClient.ValidateToken(r.Context(), &authpb.ValidateTokenRequest{Token: cookie.Value})
+	if err != nil {
+		h.logger.Error("token validation RPC failed", zap.Error(err), zap.String("path", r.URL.Path))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !authResp.Valid {
+		h.logger.Warn("invalid token", zap.String("path", r.URL.Path))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return authResp, true
+}
+
+// authAdmin validates the auth cookie and requires the admin role, returns the user id.
+func (h *ProductHandler) authAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authResp, ok := h.authUser(w, r)
+	if !ok {
+		return "", false
+	}
+	if authResp.Role != adminRole {
+		h.logger.Warn("admin route hit without admin role", zap.String("user_id", authResp.UserId), zap.String("path", r.URL.Path))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return authResp.UserId, true
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+var errCategoryCycle = errors.New("a category can't be moved under itself")
+
+type categoryRequest struct {
+	Name     *string `json:"name"`
+	Slug     *string `json:"slug"`
+	ParentID *string `json:"parent_id"` // "" moves it to the root
+	Position *int    `json:"position"`
+}
+
+func (h *ProductHandler) ListCategoriesHTTP(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryRepo.ListCategories(r.Context())
+	if err != nil {
+		h.logger.Error("err listing categories", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CategoryTree(categories))
+}
+
+func (h *ProductHandler) CreateCategoryHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == nil {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	category := &models.Category{Name: *req.Name, Slug: models.Slugify(*req.Name)}
+	if req.Slug != nil {
+		category.Slug = *req.Slug
+	}
+	parentID := ""
+	if req.ParentID != nil {
+		parentID = *req.ParentID
+	}
+	if err := h.setParent(r.Context(), category, parentID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	} else {
+		// new categories go last among their siblings
+		position, err := h.nextPosition(r.Context(), parentID)
+		if err != nil {
+			h.logger.Error("err listing categories", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		category.Position = position
+	}
+	if err := category.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.categoryRepo.CreateCategory(r.Context(), category); err != nil {
+		if errors.Is(err, database.ErrSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.logger.Error("err creating category", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.suggester.Refresh()
+	h.logger.Info("category created", zap.String("category_id", category.ID.Hex()), zap.String("actor_id", actorID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategoryHTTP renames, re-slugs, reorders or moves a category. Moving
+// rewrites the ancestors of the whole subtree, renaming updates its products.
+func (h *ProductHandler) UpdateCategoryHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	category, ok := h.targetCategory(w, r)
+	if !ok {
+		return
+	}
+	id := category.ID.Hex()
+	renamed := req.Name != nil && *req.Name != category.Name
+	moved := req.ParentID != nil && *req.ParentID != category.ParentID
+	oldAncestors := category.Ancestors
+
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Slug != nil {
+		category.Slug = *req.Slug
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+	var descendants []*models.Category
+	if moved {
+		var err error
+		descendants, err = h.categoryRepo.Descendants(r.Context(), id)
+		if err != nil {
+			h.logger.Error("err fetching descendants", zap.Error(err), zap.String("category_id", id))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		for _, d := range descendants {
+			if d.ID.Hex() == *req.ParentID {
+				http.Error(w, errCategoryCycle.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := h.setParent(r.Context(), category, *req.ParentID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := category.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.categoryRepo.UpdateCategory(r.Context(), category); err != nil {
+		if errors.Is(err, database.ErrSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.logger.Error("err updating category", zap.Error(err), zap.String("category_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// the subtree keeps its shape, only the part of the path above this node changes
+	for _, d := range descendants {
+		d.Ancestors = append(append(append([]string{}, category.Ancestors...), id), d.Ancestors[len(oldAncestors)+1:]...)
+		if err := h.categoryRepo.UpdateCategory(r.Context(), d); err != nil {
+			h.logger.Error("err moving descendant category", zap.Error(err), zap.String("category_id", d.ID.Hex()))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if renamed {
+		n, err := h.productRepo.RenameCategory(r.Context(), id, category.Name)
+		if err != nil {
+			h.logger.Error("err renaming category on products", zap.Error(err), zap.String("category_id", id))
+			http.Error(w, "category saved, but updating its products failed, retry", http.StatusInternalServerError)
+			return
+		}
+		h.logger.Info("category renamed on products", zap.String("category_id", id), zap.Int64("products", n))
+	}
+
+	h.suggester.Refresh()
+	h.logger.Info("category updated", zap.String("category_id", id), zap.String("actor_id", actorID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategoryHTTP only deletes empty leaves, products and children have to
+// be moved first.
+func (h *ProductHandler) DeleteCategoryHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	category, ok := h.targetCategory(w, r)
+	if !ok {
+		return
+	}
+	id := category.ID.Hex()
+
+	children, err := h.categoryRepo.Descendants(r.Context(), id)
+	if err != nil {
+		h.logger.Error("err fetching descendants", zap.Error(err), zap.String("category_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(children) > 0 {
+		http.Error(w, "category has subcategories", http.StatusConflict)
+		return
+	}
+	n, err := h.productRepo.CountByCategory(r.Context(), id)
+	if err != nil {
+		h.logger.Error("err counting category products", zap.Error(err), zap.String("category_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if n > 0 {
+		http.Error(w, "category still has products", http.StatusConflict)
+		return
+	}
+
+	if err := h.categoryRepo.DeleteCategory(r.Context(), id); err != nil {
+		h.logger.Error("err deleting category", zap.Error(err), zap.String("category_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.suggester.Refresh()
+	h.logger.Info("category deleted", zap.String("category_id", id), zap.String("actor_id", actorID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CategoryProductsHTTP lists the products of a category and all its
+// descendants, with the same paging and filters as /products/get.
+func (h *ProductHandler) CategoryProductsHTTP(w http.ResponseWriter, r *http.Request) {
+	category, err := h.categoryRepo.GetCategoryBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		h.logger.Error("err fetching category", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if category == nil {
+		http.Error(w, "category not found", http.StatusNotFound)
+		return
+	}
+	descendants, err := h.categoryRepo.Descendants(r.Context(), category.ID.Hex())
+	if err != nil {
+		h.logger.Error("err fetching descendants", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	ids := []string{category.ID.Hex()}
+	for _, d := range descendants {
+		ids = append(ids, d.ID.Hex())
+	}
+
+	filter, err := listFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Match = bson.M{"category_id": bson.M{"$in": ids}}
+
+	products, next, total, err := h.productRepo.ListProducts(r.Context(), filter)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err listing category products", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeProductPage(w, products, next, total)
+}
+
+func (h *ProductHandler) targetCategory(w http.ResponseWriter, r *http.Request) (*models.Category, bool) {
+	category, err := h.categoryRepo.GetCategory(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid category id", http.StatusBadRequest)
+		return nil, false
+	}
+	if category == nil {
+		http.Error(w, "category not found", http.StatusNotFound)
+		return nil, false
+	}
+	return category, true
+}
+
+// setParent points category at parentID and fills in its ancestors.
+func (h *ProductHandler) setParent(ctx context.Context, category *models.Category, parentID string) error {
+	if parentID == "" {
+		category.ParentID = ""
+		category.Ancestors = []string{}
+		return nil
+	}
+	if parentID == category.ID.Hex() {
+		return errCategoryCycle
+	}
+	parent, err := h.categoryRepo.GetCategory(ctx, parentID)
+	if err != nil || parent == nil {
+		return errors.New("parent category not found")
+	}
+	category.ParentID = parentID
+	category.Ancestors = append(append([]string{}, parent.Ancestors...), parentID)
+	return nil
+}
+
+func (h *ProductHandler) nextPosition(ctx context.Context, parentID string) (int, error) {
+	categories, err := h.categoryRepo.ListCategories(ctx)
+	if err != nil {
+		return 0, err
+	}
+	position := 0
+	for _, c := range categories {
+		if c.ParentID == parentID && c.Position >= position {
+			position = c.Position + 1
+		}
+	}
+	return position, nil
+}
+
+// resolveCategory returns the category for a product assignment.
+func (h *ProductHandler) resolveCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	category, err := h.categoryRepo.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, database.ErrCategoryNotFound
+	}
+	return category, nil
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"product-service/internal/models"
+	"strconv"
+	"strings"
+)
+
+var errETagMismatch = errors.New("if-match doesn't match the product")
+
+// setETag sends the product version as a strong etag.
+func setETag(w http.ResponseWriter, p *models.Product) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(p.Version, 10)))
+}
+
+// ifMatchVersion reads the version an update expects from If-Match, nil when
+// there is none or it is "*". Weak etags never match, it is a strong
+// comparison.
+func ifMatchVersion(r *http.Request) (*int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+	unquoted, err := strconv.Unquote(v)
+	if err != nil {
+		return nil, errETagMismatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, errETagMismatch
+	}
+	return &version, nil
+}
+
+// checkIfMatch answers 412 when If-Match names another version than the
+// product just read.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, product *models.Product) bool {
+	expected, err := ifMatchVersion(r)
+	if err == nil && expected != nil && *expected != product.Version {
+		err = errETagMismatch
+	}
+	if err != nil {
+		http.Error(w, "product was changed by someone else, reload it and retry", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// productChanged answers a write that lost the version check to another one,
+// 412 when the client sent If-Match, 409 to just retry otherwise.
+func productChanged(w http.ResponseWriter, r *http.Request) {
+	if expected, _ := ifMatchVersion(r); expected != nil {
+		http.Error(w, "product was changed by someone else, reload it and retry", http.StatusPreconditionFailed)
+		return
+	}
+	http.Error(w, "product changed while saving, retry", http.StatusConflict)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"product-service/internal/storage"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// AddImageHTTP uploads one more gallery image, multipart with image, alt and
+// primary=true to make it the primary right away. The first image always is.
+func (h *ProductHandler) AddImageHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	product, ok := h.galleryProduct(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, storage.MaxImageSize+1<<20)
+	err := r.ParseMultipartForm(10 << 20)
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		http.Error(w, storage.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot parse form data", http.StatusBadRequest)
+		return
+	}
+	primary, _ := strconv.ParseBool(r.FormValue("primary"))
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "missing image file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	url, images, err := h.storeImage(r.Context(), file)
+	if err != nil {
+		code, msg := imageErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			h.logger.Error("err storing image", zap.Error(err))
+		}
+		http.Error(w, msg, code)
+		return
+	}
+
+	img := models.GalleryImage{
+		ID:     primitive.NewObjectID().Hex(),
+		URL:    url,
+		Images: images,
+		Alt:    r.FormValue("alt"),
+	}
+	product.Gallery = append(product.Gallery, img)
+	if primary || len(product.Gallery) == 1 {
+		product.SetPrimary(img.ID)
+	}
+	h.saveGallery(w, r, product, actorID, http.StatusCreated)
+}
+
+// UpdateImageHTTP changes the alt text of an image or makes it the primary.
+func (h *ProductHandler) UpdateImageHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Alt     *string `json:"alt"`
+		Primary *bool   `json:"primary"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	product, ok := h.galleryProduct(w, r)
+	if !ok {
+		return
+	}
+
+	img := product.GalleryImage(r.PathValue("imageId"))
+	if img == nil {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	if req.Alt != nil {
+		img.Alt = *req.Alt
+	}
+	if req.Primary != nil {
+		if !*req.Primary && img.Primary {
+			http.Error(w, "make another image primary instead", http.StatusBadRequest)
+			return
+		}
+		if *req.Primary {
+			product.SetPrimary(img.ID)
+		}
+	}
+	h.saveGallery(w, r, product, actorID, http.StatusOK)
+}
+
+// DeleteImageHTTP removes an image from the gallery, when it was the primary
+// the next one takes over. The blobs stay, the same content may be used by
+// another product.
+func (h *ProductHandler) DeleteImageHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	product, ok := h.galleryProduct(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("imageId")
+	img := product.GalleryImage(id)
+	if img == nil {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	wasPrimary := img.Primary
+	gallery := make([]models.GalleryImage, 0, len(product.Gallery))
+	for _, g := range product.Gallery {
+		if g.ID != id {
+			gallery = append(gallery, g)
+		}
+	}
+	product.Gallery = gallery
+	if wasPrimary && len(gallery) > 0 {
+		product.SetPrimary(gallery[0].ID)
+	}
+	h.saveGallery(w, r, product, actorID, http.StatusOK)
+}
+
+// ReorderImagesHTTP takes every image id of the gallery in the new order.
+func (h *ProductHandler) ReorderImagesHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	product, ok := h.galleryProduct(w, r)
+	if !ok {
+		return
+	}
+
+	if len(req.IDs) != len(product.Gallery) {
+		http.Error(w, "ids must list every image of the product once", http.StatusBadRequest)
+		return
+	}
+	gallery := make([]models.GalleryImage, 0, len(req.IDs))
+	seen := map[string]bool{}
+	for _, id := range req.IDs {
+		img := product.GalleryImage(id)
+		if img == nil || seen[id] {
+			http.Error(w, "ids must list every image of the product once", http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		gallery = append(gallery, *img)
+	}
+	product.Gallery = gallery
+	h.saveGallery(w, r, product, actorID, http.StatusOK)
+}
+
+// galleryProduct loads the product of the path and checks it against
+// If-Match. Products from before
+// galleries get their single image as the first, primary, entry.
+func (h *ProductHandler) galleryProduct(w http.ResponseWriter, r *http.Request) (*models.Product, bool) {
+	id := r.PathValue("id")
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err == nil && product.DeletedAt != nil {
+		err = database.ErrProductNotFound
+	}
+	if err != nil {
+		h.logger.Warn("product not found", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "product not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !checkIfMatch(w, r, product) {
+		return nil, false
+	}
+	if len(product.Gallery) == 0 && product.Image != "" {
+		product.Gallery = []models.GalleryImage{{
+			ID:      primitive.NewObjectID().Hex(),
+			URL:     product.Image,
+			Images:  product.Images,
+			Alt:     product.Name,
+			Primary: true,
+		}}
+	}
+	return product, true
+}
+
+// saveGallery stores the edited gallery and tells the carts when the primary
+// image changed.
+func (h *ProductHandler) saveGallery(w http.ResponseWriter, r *http.Request, product *models.Product, actorID string, code int) {
+	if err := models.ValidateGallery(product.Gallery); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := product.ID.Hex()
+	updated, err := h.productRepo.SetGallery(r.Context(), id, product.Gallery, product.Version)
+	switch {
+	case errors.Is(err, database.ErrProductChanged):
+		productChanged(w, r)
+		return
+	case err != nil:
+		h.logger.Error("err saving gallery", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("gallery updated",
+		zap.String("product_id", id),
+		zap.String("actor_id", actorID),
+		zap.Int("images", len(updated.Gallery)),
+	)
+	if updated.Image != product.Image {
+		if err := h.productProducer.PublishProductUpdated(r.Context(), productUpdatedEvent(updated)); err != nil {
+			h.logger.Error("failed to publish product event", zap.Error(err), zap.String("product_id", id))
+		}
+	}
+
+	gallery := updated.Gallery
+	if gallery == nil {
+		gallery = []models.GalleryImage{}
+	}
+	setETag(w, updated)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(gallery)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"product-service/internal/imaging"
+	"product-service/internal/models"
+	"product-service/internal/storage"
+)
+
+// storeImage renders an upload in every size and format and stores them. The
+// original is not kept since it may carry EXIF (location etc.), Image becomes
+// the detail jpeg.
+func (h *ProductHandler) storeImage(ctx context.Context, r io.Reader) (string, map[string]models.ImageVariant, error) {
+	data, err := storage.ReadImage(r)
+	if err != nil {
+		return "", nil, err
+	}
+	renditions, err := imaging.Process(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	images := make(map[string]models.ImageVariant, len(renditions))
+	for _, rd := range renditions {
+		v := models.ImageVariant{Width: rd.Width, Height: rd.Height}
+		if v.JPEG, err = storage.PutContent(ctx, h.blobStore, rd.JPEG); err != nil {
+			return "", nil, err
+		}
+		if v.WebP, err = storage.PutContent(ctx, h.blobStore, rd.WebP); err != nil {
+			return "", nil, err
+		}
+		images[rd.Size] = v
+	}
+	return images["detail"].JPEG, images, nil
+}
+
+// imageErrorStatus maps storeImage errors to a status and a message safe to
+// show the client, 500 means the error should be logged.
+func imageErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, storage.ErrTooLarge), errors.Is(err, imaging.ErrTooManyPixels):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, storage.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType, err.Error()
+	case errors.Is(err, imaging.ErrInvalidImage):
+		return http.StatusBadRequest, imaging.ErrInvalidImage.Error()
+	default:
+		return http.StatusInternalServerError, "internal server error"
+	}
+}
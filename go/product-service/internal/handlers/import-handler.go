@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"product-service/internal/catalog"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	maxImportSize = 20 << 20
+	// progress is saved every this many rows
+	importSaveEvery = 100
+)
+
+// ImportHTTP starts a bulk import, the body is the csv or ndjson file. The
+// whole file is parsed up front so a broken one is rejected right away, the
+// rows are then upserted by id or sku in the background. Poll the returned job.
+func (h *ProductHandler) ImportHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	format := importFormat(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	rows, rowErrs, err := catalog.Parse(format, r.Body)
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("file is larger than %d MB, split it", maxImportSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job := &models.ImportJob{
+		Format:  format,
+		Status:  models.ImportRunning,
+		Total:   len(rows) + len(rowErrs),
+		ActorID: actorID,
+		Errors:  []models.ImportRowError{},
+	}
+	for _, e := range rowErrs {
+		job.Processed++
+		job.AddError(e)
+	}
+	if err := h.importRepo.CreateJob(r.Context(), job); err != nil {
+		h.logger.Error("err creating import job", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("import started",
+		zap.String("job_id", job.ID.Hex()),
+		zap.String("actor_id", actorID),
+		zap.Int("rows", job.Total),
+	)
+
+	// the request context ends with this response
+	go h.runImport(context.Background(), job, rows)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *ProductHandler) GetImportHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r); !ok {
+		return
+	}
+	job, err := h.importRepo.GetJob(r.Context(), r.PathValue("id"))
+	if errors.Is(err, database.ErrInvalidJobID) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err getting import job", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "import job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// ExportHTTP streams the whole catalog in the import format.
+func (h *ProductHandler) ExportHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r); !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = catalog.FormatCSV
+	}
+	contentType := "text/csv"
+	if format == catalog.FormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+
+	cw, err := catalog.NewWriter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+
+	flusher, _ := w.(http.Flusher)
+	n := 0
+	err = h.productRepo.EachProduct(r.Context(), func(p *models.Product) error {
+		if err := cw.Write(p); err != nil {
+			return err
+		}
+		if n++; n%importSaveEvery == 0 {
+			if err := cw.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = cw.Flush()
+	}
+	// the header is out already, all that's left is cutting the stream short
+	if err != nil {
+		h.logger.Error("export failed", zap.Error(err), zap.Int("written", n))
+		return
+	}
+	h.logger.Info("catalog exported", zap.String("format", format), zap.Int("products", n))
+}
+
+// importFormat is the format query param, or else guessed from the content type.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return catalog.FormatNDJSON
+	default:
+		return catalog.FormatCSV
+	}
+}
+
+func (h *ProductHandler) runImport(ctx context.Context, job *models.ImportJob, rows []catalog.Row) {
+	defer func() {
+		if p := recover(); p != nil {
+			h.logger.Error("import panicked", zap.Any("panic", p), zap.String("job_id", job.ID.Hex()))
+			job.Status = models.ImportFailed
+			job.Error = "internal error"
+			job.FinishedAt = time.Now()
+			h.saveJob(ctx, job)
+		}
+	}()
+
+	for i, row := range rows {
+		created, err := h.importRow(ctx, job.ActorID, row)
+		job.Processed++
+		switch {
+		case err != nil:
+			job.AddError(models.ImportRowError{Line: row.Line, ID: row.ID, SKU: row.SKU, Error: err.Error()})
+		case created:
+			job.Created++
+		default:
+			job.Updated++
+		}
+		if (i+1)%importSaveEvery == 0 {
+			h.saveJob(ctx, job)
+		}
+	}
+
+	job.Status = models.ImportDone
+	job.FinishedAt = time.Now()
+	h.saveJob(ctx, job)
+	h.logger.Info("import finished",
+		zap.String("job_id", job.ID.Hex()),
+		zap.Int("created", job.Created),
+		zap.Int("updated", job.Updated),
+		zap.Int("failed", job.Failed),
+	)
+}
+
+func (h *ProductHandler) saveJob(ctx context.Context, job *models.ImportJob) {
+	if err := h.importRepo.SaveJob(ctx, job); err != nil {
+		h.logger.Error("err saving import job", zap.Error(err), zap.String("job_id", job.ID.Hex()))
+	}
+}
+
+// importRow creates or updates the product with the row's id or sku, true if
+// it was created. Errors are meant for the job's row errors.
+func (h *ProductHandler) importRow(ctx context.Context, actorID string, row catalog.Row) (bool, error) {
+	category := row.Category
+	if row.CategoryID != "" {
+		c, err := h.resolveCategory(ctx, row.CategoryID)
+		if err != nil {
+			return false, errors.New("invalid category_id")
+		}
+		category = c.Name
+	}
+
+	existing, err := h.findImported(ctx, row)
+	if err != nil {
+		return false, err
+	}
+
+	if existing == nil {
+		p := &models.Product{
+			Name:        row.Name,
+			SKU:         row.SKU,
+			Category:    category,
+			CategoryID:  row.CategoryID,
+			Image:       row.Image,
+			PriceCents:  row.PriceCents,
+			Description: row.Description,
+		}
+		if row.Image != "" {
+			p.Gallery = []models.GalleryImage{{
+				ID:      primitive.NewObjectID().Hex(),
+				URL:     row.Image,
+				Alt:     row.Name,
+				Primary: true,
+			}}
+		}
+		created, err := h.productRepo.CreateProduct(ctx, p)
+		if errors.Is(err, database.ErrSKUTaken) {
+			return false, err
+		}
+		if err != nil {
+			h.logger.Error("err creating imported product", zap.Error(err), zap.String("sku", row.SKU))
+			return false, errors.New("creating product failed")
+		}
+		h.recordPrice(ctx, created, models.PriceImport, actorID, "")
+		h.suggester.Refresh()
+		if row.Stock != nil && *row.Stock > 0 {
+			if err := h.setImportedStock(ctx, actorID, created, *row.Stock, models.StockRestock); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	changes := map[string]any{}
+	if existing.Name != row.Name {
+		changes["name"] = row.Name
+	}
+	if existing.Description != row.Description {
+		changes["description"] = row.Description
+	}
+	if existing.Category != category {
+		changes["category"] = category
+	}
+	if existing.CategoryID != row.CategoryID {
+		changes["category_id"] = row.CategoryID
+	}
+	if existing.PriceCents != row.PriceCents {
+		changes["price"] = row.PriceCents
+	}
+	// an empty image keeps the current one, a different url replaces the
+	// gallery like a plain url update does
+	if row.Image != "" && existing.Image != row.Image {
+		changes["image"] = row.Image
+		changes["images"] = nil
+		changes["gallery"] = []models.GalleryImage{{
+			ID:      primitive.NewObjectID().Hex(),
+			URL:     row.Image,
+			Alt:     row.Name,
+			Primary: true,
+		}}
+	}
+
+	product := existing
+	if len(changes) > 0 {
+		id := existing.ID.Hex()
+		product, err = h.productRepo.UpdateProduct(ctx, id, changes, &existing.Version)
+		if errors.Is(err, database.ErrVersionMismatch) {
+			return false, errors.New("product was edited during the import, import the row again")
+		}
+		if err != nil {
+			h.logger.Error("err updating imported product", zap.Error(err), zap.String("product_id", id))
+			return false, errors.New("updating product failed")
+		}
+		if _, ok := changes["price"]; ok {
+			h.recordPrice(ctx, product, models.PriceImport, actorID, "")
+		}
+		if err := h.productProducer.PublishProductUpdated(ctx, productUpdatedEvent(product)); err != nil {
+			h.logger.Error("failed to publish product event", zap.Error(err), zap.String("product_id", id))
+		}
+	}
+
+	if row.Stock != nil && *row.Stock != product.Stock.OnHand {
+		if len(product.Variants) > 0 {
+			return false, errors.New("product has variants, stock is set per variant")
+		}
+		if err := h.setImportedStock(ctx, actorID, product, *row.Stock-product.Stock.OnHand, models.StockCorrection); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// findImported looks up the product a row updates, nil if the row is a new
+// product.
+func (h *ProductHandler) findImported(ctx context.Context, row catalog.Row) (*models.Product, error) {
+	if row.ID != "" {
+		existing, err := h.productRepo.GetProductById(ctx, row.ID)
+		if err != nil || existing == nil || existing.ID.IsZero() {
+			return nil, errors.New("no product with that id")
+		}
+		if existing.DeletedAt != nil {
+			return nil, errors.New("id belongs to a deleted product")
+		}
+		// an empty sku keeps the product's, skus aren't changed by import
+		if row.SKU != "" && row.SKU != existing.SKU {
+			return nil, errors.New("sku doesn't match the product with that id")
+		}
+		return existing, nil
+	}
+
+	existing, err := h.productRepo.GetProductBySKU(ctx, row.SKU)
+	if err != nil {
+		h.logger.Error("err finding product by sku", zap.Error(err), zap.String("sku", row.SKU))
+		return nil, errors.New("internal error")
+	}
+	if existing != nil && existing.DeletedAt != nil {
+		return nil, errors.New("sku belongs to a deleted product")
+	}
+	if existing != nil && existing.SKU != row.SKU {
+		return nil, errors.New("sku belongs to a variant, edit it with the variants endpoint")
+	}
+	return existing, nil
+}
+
+// setImportedStock moves on hand by delta through the ledger.
+func (h *ProductHandler) setImportedStock(ctx context.Context, actorID string, product *models.Product, delta int64, reason models.StockReason) error {
+	id := product.ID.Hex()
+	stock, err := h.inventoryRepo.AdjustStock(ctx, id, &models.StockMovement{
+		Delta:   delta,
+		Reason:  reason,
+		Note:    "import",
+		ActorID: actorID,
+	})
+	if err != nil {
+		h.logger.Warn("err setting imported stock", zap.Error(err), zap.String("product_id", id))
+		return fmt.Errorf("setting stock failed: %v", err)
+	}
+	h.publishStockChanged(ctx, id, "", delta, reason, stock, stock.Available()-delta)
+	return nil
+}
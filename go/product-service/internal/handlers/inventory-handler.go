@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const stockMovementLimit = 50
+
+// GetStockHTTP returns the current inventory and the latest ledger entries.
+func (h *ProductHandler) GetStockHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Warn("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.authAdmin(w, r); !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing product id", http.StatusBadRequest)
+		return
+	}
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err != nil {
+		h.logger.Warn("product not found", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+	movements, err := h.inventoryRepo.ListMovements(r.Context(), id, stockMovementLimit)
+	if err != nil {
+		h.logger.Error("err listing stock movements", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type variantStock struct {
+		ID    string           `json:"id"`
+		SKU   string           `json:"sku"`
+		Stock models.Inventory `json:"stock"`
+	}
+	variants := make([]variantStock, 0, len(product.Variants))
+	for _, v := range product.Variants {
+		variants = append(variants, variantStock{ID: v.ID, SKU: v.SKU, Stock: v.Stock})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"product_id": id,
+		"stock":      product.Stock,
+		"variants":   variants,
+		"movements":  movements,
+	})
+}
+
+// AdjustStockHTTP adds (or with a negative delta removes) stock on hand.
+// A reason is required so the ledger explains every change.
+func (h *ProductHandler) AdjustStockHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Warn("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing product id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		VariantID string             `json:"variant_id"`
+		Delta     int64              `json:"delta"`
+		Reason    models.StockReason `json:"reason"`
+		Note      string             `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err decoding request body", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Delta == 0 {
+		http.Error(w, "delta can't be 0", http.StatusBadRequest)
+		return
+	}
+	if !req.Reason.Valid() {
+		http.Error(w, "reason must be one of restock, return, damaged, lost, correction", http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.inventoryRepo.AdjustStock(r.Context(), id, &models.StockMovement{
+		VariantID: req.VariantID,
+		Delta:     req.Delta,
+		Reason:    req.Reason,
+		Note:      req.Note,
+		ActorID:   actorID,
+	})
+	switch {
+	case errors.Is(err, database.ErrProductNotFound), errors.Is(err, database.ErrVariantNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrVariantRequired):
+		http.Error(w, "product has variants, variant_id is required", http.StatusBadRequest)
+		return
+	case errors.Is(err, database.ErrInsufficientStock):
+		http.Error(w, "stock on hand can't drop below reserved", http.StatusConflict)
+		return
+	case errors.Is(err, database.ErrStockContention):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("err adjusting stock", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("stock adjusted",
+		zap.String("product_id", id),
+		zap.String("variant_id", req.VariantID),
+		zap.String("actor_id", actorID),
+		zap.Int64("delta", req.Delta),
+		zap.String("reason", string(req.Reason)),
+	)
+	h.publishStockChanged(r.Context(), id, req.VariantID, req.Delta, req.Reason, stock, stock.Available()-req.Delta)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stock)
+}
+
+// publishStockChanged also sends OutOfStock when this change used up the last
+// available unit, before is what was available until now.
+func (h *ProductHandler) publishStockChanged(ctx context.Context, productID, variantID string, delta int64, reason models.StockReason, stock *models.Inventory, before int64) {
+	now := time.Now()
+	event := models.StockChangedEvent{
+		ProductID: productID,
+		VariantID: variantID,
+		Delta:     delta,
+		Reason:    reason,
+		OnHand:    stock.OnHand,
+		Reserved:  stock.Reserved,
+		Available: stock.Available(),
+		Time:      now,
+	}
+	if err := h.productProducer.PublishStockChanged(ctx, event); err != nil {
+		h.logger.Error("failed to publish stock changed event", zap.Error(err), zap.String("product_id", productID))
+	}
+
+	if stock.Available() <= 0 && before > 0 {
+		if err := h.productProducer.PublishOutOfStock(ctx, models.OutOfStockEvent{ProductID: productID, VariantID: variantID, Time: now}); err != nil {
+			h.logger.Error("failed to publish out of stock event", zap.Error(err), zap.String("product_id", productID))
+		}
+	}
+}
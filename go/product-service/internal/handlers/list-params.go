@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listFilter reads the listing params shared by /products/get and /products/search:
+// ?cursor=&limit=&sort=price|created_at|name|rating&order=asc|desc&category=&min_price=&max_price=
+// prices are in cents like everywhere else.
+func listFilter(r *http.Request) (database.ProductFilter, error) {
+	q := r.URL.Query()
+	filter := database.ProductFilter{
+		Category: q.Get("category"),
+		Sort:     q.Get("sort"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	switch filter.Sort {
+	case "", "price", "created_at", "name", "rating":
+	default:
+		return filter, errors.New("sort must be one of price, created_at, name, rating")
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+
+	limit, err := pageLimit(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit = limit
+	for param, dst := range map[string]**int64{
+		"min_price": &filter.MinPrice,
+		"max_price": &filter.MaxPrice,
+	} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || price < 0 {
+			return filter, fmt.Errorf("invalid %s", param)
+		}
+		*dst = &price
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, errors.New("min_price is greater than max_price")
+	}
+	return filter, nil
+}
+
+// pageLimit reads ?limit=, defaulting to defaultPageSize and capped at maxPageSize.
+func pageLimit(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || limit < 1 {
+		return 0, errors.New("invalid limit")
+	}
+	return min(limit, maxPageSize), nil
+}
+
+func writeProductPage(w http.ResponseWriter, products []*models.Product, next string, total int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"products":    products,
+		"next_cursor": next,
+		"total":       total,
+	})
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// retries when an admin edits the product while a price change is applied
+const priceChangeAttempts = 3
+
+// a claimed schedule not finished by then is taken over by the next tick of
+// any instance, it is far longer than a price change takes
+const priceClaimTimeout = 5 * time.Minute
+
+// PricesHTTP is the price history, newest first, and the product's schedules.
+func (h *ProductHandler) PricesHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r); !ok {
+		return
+	}
+	limit, err := pageLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := r.PathValue("id")
+	history, err := h.priceRepo.ListHistory(r.Context(), id, limit)
+	if err != nil {
+		h.logger.Error("err fetching price history", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	schedules, err := h.priceRepo.ListSchedules(r.Context(), id)
+	if err != nil {
+		h.logger.Error("err fetching price schedules", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"history":   history,
+		"schedules": schedules,
+	})
+}
+
+// SchedulePriceHTTP plans a new regular price from starts_at on, or with
+// kind sale a sale price between starts_at and ends_at.
+func (h *ProductHandler) SchedulePriceHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Kind       models.ScheduleKind `json:"kind"`
+		PriceCents int64               `json:"price"`
+		StartsAt   time.Time           `json:"starts_at"`
+		EndsAt     *time.Time          `json:"ends_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.SchedulePrice
+	}
+	switch {
+	case req.Kind != models.SchedulePrice && req.Kind != models.ScheduleSale:
+		http.Error(w, "kind must be price or sale", http.StatusBadRequest)
+		return
+	case req.PriceCents <= 0:
+		http.Error(w, "price must be positive", http.StatusBadRequest)
+		return
+	case !req.StartsAt.After(time.Now()):
+		http.Error(w, "starts_at must be in the future, update the product to change the price now", http.StatusBadRequest)
+		return
+	case req.Kind == models.ScheduleSale && (req.EndsAt == nil || !req.EndsAt.After(req.StartsAt)):
+		http.Error(w, "a sale needs an ends_at after starts_at", http.StatusBadRequest)
+		return
+	case req.Kind == models.SchedulePrice && req.EndsAt != nil:
+		http.Error(w, "only sales have an ends_at", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err != nil || product.DeletedAt != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+	if len(product.Variants) > 0 {
+		http.Error(w, "product has variants, their prices are set per variant", http.StatusBadRequest)
+		return
+	}
+	if req.Kind == models.ScheduleSale && req.PriceCents >= regularPrice(product) {
+		http.Error(w, "sale price must be below the regular price", http.StatusBadRequest)
+		return
+	}
+
+	schedule := &models.PriceSchedule{
+		ProductID:  id,
+		Kind:       req.Kind,
+		PriceCents: req.PriceCents,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		ActorID:    actorID,
+	}
+	err = h.priceRepo.CreateSchedule(r.Context(), schedule)
+	if errors.Is(err, database.ErrSaleOverlaps) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err creating price schedule", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("price scheduled",
+		zap.String("product_id", id),
+		zap.String("schedule_id", schedule.ID.Hex()),
+		zap.String("kind", string(schedule.Kind)),
+		zap.String("actor_id", actorID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// CancelScheduleHTTP drops a pending schedule, a running sale ends right away.
+func (h *ProductHandler) CancelScheduleHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+	schedule, err := h.priceRepo.GetSchedule(r.Context(), r.PathValue("id"), r.PathValue("scheduleId"))
+	if errors.Is(err, database.ErrScheduleNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err fetching price schedule", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var to models.ScheduleStatus
+	var msg string
+	switch schedule.Status {
+	case models.SchedulePending:
+		to, msg = models.ScheduleCanceled, "canceled"
+	case models.ScheduleActive:
+		to, msg = models.ScheduleDone, "ended early"
+	default:
+		http.Error(w, fmt.Sprintf("schedule is already %s", schedule.Status), http.StatusConflict)
+		return
+	}
+	changed, err := h.priceRepo.SetScheduleStatus(r.Context(), schedule.ID, schedule.Status, to, fmt.Sprintf("%s by %s", msg, actorID))
+	if err != nil {
+		h.logger.Error("err canceling price schedule", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		// the scheduler got to it first
+		http.Error(w, "schedule changed meanwhile, reload it", http.StatusConflict)
+		return
+	}
+	if schedule.Status == models.ScheduleActive {
+		if err := h.changePrice(r.Context(), schedule.ProductID, models.PriceSaleEnd, actorID, schedule.ID.Hex(), endSale); err != nil {
+			h.logger.Error("err ending sale", zap.Error(err), zap.String("product_id", schedule.ProductID))
+			http.Error(w, "schedule canceled but restoring the regular price failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.logger.Info("price schedule canceled", zap.String("schedule_id", schedule.ID.Hex()), zap.String("actor_id", actorID))
+
+	schedule.Status = to
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// RunPriceScheduler applies due schedules until ctx is done. Claims are
+// atomic so every instance of the service can run it.
+func (h *ProductHandler) RunPriceScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for i := 0; i < sweepBatchSize; i++ {
+				schedule, to, err := h.priceRepo.ClaimDue(ctx, time.Now(), priceClaimTimeout)
+				if err != nil {
+					h.logger.Error("err claiming price schedule", zap.Error(err))
+					break
+				}
+				// a failed one is due again right away, it waits for the next tick
+				if schedule == nil || !h.applySchedule(ctx, schedule, to) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// applySchedule runs a claimed schedule and only then moves it from applying
+// to to, the status it has once applied. A claim taken over after a crash runs
+// again, so every change is a no-op when already made. False when it failed
+// and was put back.
+func (h *ProductHandler) applySchedule(ctx context.Context, schedule *models.PriceSchedule, to models.ScheduleStatus) bool {
+	id := schedule.ProductID
+	var reason models.PriceReason
+	var change func(*models.Product) map[string]any
+	switch {
+	case schedule.Status == models.ScheduleActive:
+		reason, change = models.PriceSaleEnd, endSale
+	case schedule.Kind == models.ScheduleSale && !schedule.EndsAt.After(time.Now()):
+		// the service was down for the whole sale, or died while starting it
+		// and the sale price is already on
+		to = models.ScheduleDone
+		reason, change = models.PriceSaleEnd, func(p *models.Product) map[string]any {
+			if p.SaleEndsAt == nil || !p.SaleEndsAt.Equal(*schedule.EndsAt) {
+				return nil
+			}
+			return endSale(p)
+		}
+	case schedule.Kind == models.ScheduleSale:
+		reason, change = models.PriceSaleStart, func(p *models.Product) map[string]any {
+			if p.CompareAtCents > 0 && p.PriceCents == schedule.PriceCents && p.SaleEndsAt != nil && p.SaleEndsAt.Equal(*schedule.EndsAt) {
+				return nil
+			}
+			return map[string]any{
+				"price":            schedule.PriceCents,
+				"compare_at_price": regularPrice(p),
+				"sale_ends_at":     schedule.EndsAt,
+			}
+		}
+	default:
+		reason, change = models.PriceScheduled, func(p *models.Product) map[string]any {
+			// during a sale the new price is what it ends on
+			if p.CompareAtCents > 0 {
+				if p.CompareAtCents == schedule.PriceCents {
+					return nil
+				}
+				return map[string]any{"compare_at_price": schedule.PriceCents}
+			}
+			if p.PriceCents == schedule.PriceCents {
+				return nil
+			}
+			return map[string]any{"price": schedule.PriceCents}
+		}
+	}
+
+	err := h.changePrice(ctx, id, reason, schedule.ActorID, schedule.ID.Hex(), change)
+	switch {
+	case errors.Is(err, database.ErrProductNotFound):
+		h.finishSchedule(ctx, schedule, models.ScheduleCanceled, "product deleted")
+	case err != nil:
+		// back to where it was, the next tick tries again
+		h.logger.Error("err applying price schedule", zap.Error(err), zap.String("schedule_id", schedule.ID.Hex()))
+		h.finishSchedule(ctx, schedule, schedule.Status, err.Error())
+		return false
+	default:
+		h.finishSchedule(ctx, schedule, to, "")
+		h.logger.Info("price schedule applied",
+			zap.String("product_id", id),
+			zap.String("schedule_id", schedule.ID.Hex()),
+			zap.String("reason", string(reason)),
+		)
+	}
+	return true
+}
+
+// finishSchedule moves a claimed schedule out of applying. If that fails the
+// claim times out and the schedule is applied again, which is a no-op.
+func (h *ProductHandler) finishSchedule(ctx context.Context, schedule *models.PriceSchedule, to models.ScheduleStatus, errMsg string) {
+	changed, err := h.priceRepo.SetScheduleStatus(ctx, schedule.ID, models.ScheduleApplying, to, errMsg)
+	if err != nil {
+		h.logger.Error("err finishing price schedule", zap.Error(err), zap.String("schedule_id", schedule.ID.Hex()))
+		return
+	}
+	if !changed {
+		// our claim timed out and another instance took it over
+		h.logger.Warn("price schedule claim lost", zap.String("schedule_id", schedule.ID.Hex()))
+	}
+}
+
+// changePrice applies the changes change returns for the current product,
+// records the new price and tells the carts. change returning nil is a no-op.
+func (h *ProductHandler) changePrice(ctx context.Context, id string, reason models.PriceReason, actorID, scheduleID string, change func(*models.Product) map[string]any) error {
+	for attempt := 0; attempt < priceChangeAttempts; attempt++ {
+		product, err := h.productRepo.GetProductById(ctx, id)
+		if err != nil || product.DeletedAt != nil {
+			return database.ErrProductNotFound
+		}
+		changes := change(product)
+		if changes == nil {
+			return nil
+		}
+		updated, err := h.productRepo.UpdateProduct(ctx, id, changes, &product.Version)
+		if errors.Is(err, database.ErrVersionMismatch) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		h.recordPrice(ctx, updated, reason, actorID, scheduleID)
+		if err := h.productProducer.PublishProductUpdated(ctx, productUpdatedEvent(updated)); err != nil {
+			h.logger.Error("failed to publish product event", zap.Error(err), zap.String("product_id", id))
+		}
+		return nil
+	}
+	return database.ErrVersionMismatch
+}
+
+// updateProduct applies a manual edit. During a sale a new price is what the
+// sale ends on, like a scheduled change, so it goes to compare_at_price and
+// the sale price stays. That depends on the product as it is written, a
+// concurrent change is retried unless the caller expected a version.
+func (h *ProductHandler) updateProduct(ctx context.Context, id string, changes map[string]any, expectedVersion *int64) (*models.Product, error) {
+	if _, ok := changes["price"]; !ok {
+		return h.productRepo.UpdateProduct(ctx, id, changes, expectedVersion)
+	}
+	for attempt := 0; attempt < priceChangeAttempts; attempt++ {
+		product, err := h.productRepo.GetProductById(ctx, id)
+		if err != nil || product.DeletedAt != nil {
+			return nil, database.ErrProductNotFound
+		}
+		if expectedVersion != nil && *expectedVersion != product.Version {
+			return nil, database.ErrVersionMismatch
+		}
+		updated, err := h.productRepo.UpdateProduct(ctx, id, manualChanges(product, changes), &product.Version)
+		if errors.Is(err, database.ErrVersionMismatch) && expectedVersion == nil {
+			continue
+		}
+		return updated, err
+	}
+	return nil, database.ErrVersionMismatch
+}
+
+// manualChanges moves a manual price onto compare_at_price while p is on sale.
+func manualChanges(p *models.Product, changes map[string]any) map[string]any {
+	if p.CompareAtCents == 0 {
+		return changes
+	}
+	moved := make(map[string]any, len(changes))
+	for k, v := range changes {
+		moved[k] = v
+	}
+	moved["compare_at_price"] = moved["price"]
+	delete(moved, "price")
+	return moved
+}
+
+// recordPrice adds the product's current prices to its history. A failure is
+// only logged, the price change itself already happened.
+func (h *ProductHandler) recordPrice(ctx context.Context, p *models.Product, reason models.PriceReason, actorID, scheduleID string) {
+	err := h.priceRepo.RecordPrice(ctx, &models.PriceRecord{
+		ProductID:      p.ID.Hex(),
+		PriceCents:     p.PriceCents,
+		CompareAtCents: p.CompareAtCents,
+		Reason:         reason,
+		ScheduleID:     scheduleID,
+		ActorID:        actorID,
+	})
+	if err != nil {
+		h.logger.Error("err recording price history", zap.Error(err), zap.String("product_id", p.ID.Hex()))
+	}
+}
+
+// regularPrice is the price outside of sales.
+func regularPrice(p *models.Product) int64 {
+	if p.CompareAtCents > 0 {
+		return p.CompareAtCents
+	}
+	return p.PriceCents
+}
+
+func endSale(p *models.Product) map[string]any {
+	if p.CompareAtCents == 0 {
+		return nil
+	}
+	return map[string]any{
+		"price":            p.CompareAtCents,
+		"compare_at_price": int64(0),
+		"sale_ends_at":     nil,
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeRepo holds one product and applies the price fields of an update when
+// the version matches. Methods it doesn't implement panic through the nil
+// embedded interface.
+type fakeRepo struct {
+	database.ProductRepository
+	product *models.Product
+	// runs after a read, like a write from another request would
+	afterRead func(p *models.Product)
+}
+
+func (r *fakeRepo) GetProductById(_ context.Context, _ string) (*models.Product, error) {
+	copied := *r.product
+	if r.afterRead != nil {
+		r.afterRead(r.product)
+		r.afterRead = nil
+	}
+	return &copied, nil
+}
+
+func (r *fakeRepo) UpdateProduct(_ context.Context, _ string, changes map[string]interface{}, expectedVersion *int64) (*models.Product, error) {
+	if expectedVersion != nil && *expectedVersion != r.product.Version {
+		return nil, database.ErrVersionMismatch
+	}
+	if v, ok := changes["price"].(int64); ok {
+		r.product.PriceCents = v
+	}
+	if v, ok := changes["compare_at_price"].(int64); ok {
+		r.product.CompareAtCents = v
+	}
+	r.product.Version++
+	copied := *r.product
+	return &copied, nil
+}
+
+func TestUpdateProductPriceDuringSale(t *testing.T) {
+	tests := []struct {
+		name                string
+		price, compareAt    int64
+		afterRead           func(p *models.Product)
+		wantPrice, wantEnds int64
+	}{
+		{name: "no sale", price: 1000, wantPrice: 1200, wantEnds: 1200},
+		{name: "on sale", price: 800, compareAt: 1000, wantPrice: 800, wantEnds: 1200},
+		{
+			name: "sale starts while updating", price: 1000,
+			afterRead: func(p *models.Product) {
+				p.PriceCents, p.CompareAtCents = 800, 1000
+				p.Version++
+			},
+			wantPrice: 800, wantEnds: 1200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{
+				product:   &models.Product{ID: primitive.NewObjectID(), PriceCents: tt.price, CompareAtCents: tt.compareAt},
+				afterRead: tt.afterRead,
+			}
+			h := &ProductHandler{productRepo: repo}
+
+			updated, err := h.updateProduct(context.Background(), repo.product.ID.Hex(), map[string]any{"price": int64(1200)}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.PriceCents != tt.wantPrice {
+				t.Errorf("price = %d, want %d", updated.PriceCents, tt.wantPrice)
+			}
+			// the manual price survives the end of the sale
+			if changes := endSale(updated); changes != nil {
+				updated.PriceCents = changes["price"].(int64)
+			}
+			if updated.PriceCents != tt.wantEnds {
+				t.Errorf("price after the sale = %d, want %d", updated.PriceCents, tt.wantEnds)
+			}
+		})
+	}
+}
+
+func TestUpdateProductExpectedVersion(t *testing.T) {
+	repo := &fakeRepo{product: &models.Product{ID: primitive.NewObjectID(), PriceCents: 1000, Version: 2}}
+	h := &ProductHandler{productRepo: repo}
+
+	stale := int64(1)
+	_, err := h.updateProduct(context.Background(), repo.product.ID.Hex(), map[string]any{"price": int64(1200)}, &stale)
+	if !errors.Is(err, database.ErrVersionMismatch) {
+		t.Fatalf("err = %v, want ErrVersionMismatch", err)
+	}
+	if repo.product.PriceCents != 1000 {
+		t.Errorf("price = %d, the stale update was written", repo.product.PriceCents)
+	}
+}
@@ -3,21 +3,22 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"grpc_module/auth/authpb"
+	"grpc_module/mtls"
 	"grpc_module/product/productpb"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"product-service/internal/database"
 	"product-service/internal/kafka"
 	"product-service/internal/models"
+	"product-service/internal/search"
+	"product-service/internal/storage"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -26,14 +27,34 @@ import (
 type ProductHandler struct {
 	productpb.UnimplementedProductServiceServer
 	productRepo     database.ProductRepository
+	inventoryRepo   database.InventoryRepository
+	categoryRepo    database.CategoryRepository
+	reviewRepo      database.ReviewRepository
+	importRepo      database.ImportJobRepository
+	priceRepo       database.PriceRepository
+	recsRepo        database.RecommendationRepository
+	searchLogRepo   database.SearchLogRepository
 	logger          *zap.Logger
 	productProducer *kafka.ProductProducer
 	authClient      authpb.AuthServiceClient
+	searchEngine    search.SearchEngine
+	suggester       *search.Suggester
+	blobStore       storage.BlobStore
 }
 
-func NewProductHandler(repo database.ProductRepository, logger *zap.Logger, authClient authpb.AuthServiceClient, producer *kafka.ProductProducer) *ProductHandler {
+func NewProductHandler(repo database.ProductRepository, inventoryRepo database.InventoryRepository, categoryRepo database.CategoryRepository, reviewRepo database.ReviewRepository, importRepo database.ImportJobRepository, priceRepo database.PriceRepository, recsRepo database.RecommendationRepository, searchLogRepo database.SearchLogRepository, searchEngine search.SearchEngine, suggester *search.Suggester, blobStore storage.BlobStore, logger *zap.Logger, authClient authpb.AuthServiceClient, producer *kafka.ProductProducer) *ProductHandler {
 	return &ProductHandler{
 		productRepo:     repo,
+		inventoryRepo:   inventoryRepo,
+		categoryRepo:    categoryRepo,
+		reviewRepo:      reviewRepo,
+		importRepo:      importRepo,
+		priceRepo:       priceRepo,
+		recsRepo:        recsRepo,
+		searchLogRepo:   searchLogRepo,
+		searchEngine:    searchEngine,
+		suggester:       suggester,
+		blobStore:       blobStore,
 		logger:          logger,
 		authClient:      authClient,
 		productProducer: producer,
@@ -41,27 +62,67 @@ func NewProductHandler(repo database.ProductRepository, logger *zap.Logger, auth
 }
 func (h *ProductHandler) Routes() http.Handler {
 	mux := http.NewServeMux()
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	// s3 urls point at the bucket, only local blobs are served from here
+	if local, ok := h.blobStore.(*storage.LocalStore); ok {
+		mux.Handle("/uploads/", http.StripPrefix("/uploads/", local.Handler()))
+	}
 	mux.HandleFunc("/product", h.CreateProductHTTP)
 	mux.HandleFunc("/products/get", h.GetAllProductsHTTP)
+	// one product with its etag, for If-Match on the writes
+	mux.HandleFunc("GET /products/item", h.GetProductByIdHTTP)
 	mux.HandleFunc("/products/search", h.SearchProductHTTP)
+	mux.HandleFunc("GET /products/suggest", h.SuggestHTTP)
 	mux.HandleFunc("/products/update", h.UpdateProductHTTP)
 	mux.HandleFunc("/products/delete", h.DeleteProductHTTP)
+	mux.HandleFunc("/products/stock", h.GetStockHTTP)
+	mux.HandleFunc("/products/stock/adjust", h.AdjustStockHTTP)
+	mux.HandleFunc("/products/variants", h.SetVariantsHTTP)
+	mux.HandleFunc("GET /categories", h.ListCategoriesHTTP)
+	mux.HandleFunc("POST /categories", h.CreateCategoryHTTP)
+	mux.HandleFunc("PUT /categories/{id}", h.UpdateCategoryHTTP)
+	mux.HandleFunc("DELETE /categories/{id}", h.DeleteCategoryHTTP)
+	mux.HandleFunc("GET /categories/{slug}/products", h.CategoryProductsHTTP)
+	mux.HandleFunc("GET /products/{id}/reviews", h.ListReviewsHTTP)
+	mux.HandleFunc("POST /products/{id}/reviews", h.CreateReviewHTTP)
+	mux.HandleFunc("PUT /products/{id}/reviews/mine", h.UpdateMyReviewHTTP)
+	mux.HandleFunc("DELETE /products/{id}/reviews/mine", h.DeleteMyReviewHTTP)
+	mux.HandleFunc("GET /reviews", h.ListReviewQueueHTTP)
+	mux.HandleFunc("POST /reviews/{id}/moderate", h.ModerateReviewHTTP)
+	mux.HandleFunc("POST /products/{id}/images", h.AddImageHTTP)
+	mux.HandleFunc("PUT /products/{id}/images/order", h.ReorderImagesHTTP)
+	mux.HandleFunc("PUT /products/{id}/images/{imageId}", h.UpdateImageHTTP)
+	mux.HandleFunc("DELETE /products/{id}/images/{imageId}", h.DeleteImageHTTP)
+	mux.HandleFunc("GET /products/{id}/prices", h.PricesHTTP)
+	mux.HandleFunc("POST /products/{id}/prices/schedules", h.SchedulePriceHTTP)
+	mux.HandleFunc("DELETE /products/{id}/prices/schedules/{scheduleId}", h.CancelScheduleHTTP)
+	mux.HandleFunc("GET /products/{id}/recommendations", h.RecommendationsHTTP)
+	mux.HandleFunc("POST /catalog/import", h.ImportHTTP)
+	mux.HandleFunc("GET /catalog/import/{id}", h.GetImportHTTP)
+	mux.HandleFunc("GET /catalog/export", h.ExportHTTP)
 	return mux
 }
 func (h *ProductHandler) GetAllProductsHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	products, err := h.productRepo.GetAllProducts(ctx)
+	filter, err := listFilter(r)
 	if err != nil {
-		h.logger.Error("err fetching all products")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	products, next, total, err := h.productRepo.ListProducts(ctx, filter)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err fetching all products", zap.Error(err))
 		http.Error(w, "couldn't fetch products ", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(products)
+	writeProductPage(w, products, next, total)
 }
 
 func (h *ProductHandler) CreateProductHTTP(w http.ResponseWriter, r *http.Request) {
@@ -94,7 +155,13 @@ func (h *ProductHandler) CreateProductHTTP(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	// the image plus a little room for the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, storage.MaxImageSize+1<<20)
 	err = r.ParseMultipartForm(10 << 20)
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		http.Error(w, storage.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		h.logger.Warn("failed to parse multipart form",
 			zap.Error(err),
@@ -107,8 +174,24 @@ func (h *ProductHandler) CreateProductHTTP(w http.ResponseWriter, r *http.Reques
 
 	name := r.FormValue("name")
 	category := r.FormValue("category")
+	categoryID := r.FormValue("category_id")
 	priceStr := r.FormValue("pricecents")
 	description := r.FormValue("description")
+	alt := r.FormValue("alt")
+	if alt == "" {
+		alt = name
+	}
+
+	// a category id wins over the free-text name
+	if categoryID != "" {
+		c, err := h.resolveCategory(r.Context(), categoryID)
+		if err != nil {
+			h.logger.Warn("invalid category id", zap.String("category_id", categoryID), zap.Error(err))
+			http.Error(w, "invalid category_id", http.StatusBadRequest)
+			return
+		}
+		category = c.Name
+	}
 
 	if name == "" || priceStr == "" || category == "" || description == "" {
 		h.logger.Warn("missing required fields")
@@ -123,45 +206,49 @@ func (h *ProductHandler) CreateProductHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	file, handler, err := r.FormFile("image")
-	if err != nil {
-		h.logger.Warn("missing image file", zap.Error(err))
-		http.Error(w, "missing image file", http.StatusBadRequest)
-		return
-	}
-	defer file.Close()
-
-	uploadDir := "./uploads" // not using any cloud service yet...
-
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		err = os.MkdirAll(uploadDir, os.ModePerm)
-		if err != nil {
-			h.logger.Error("creating dir err", zap.Error(err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+	// initial stock is optional, it goes through the ledger like any other adjustment
+	var stock int64
+	if v := r.FormValue("stock"); v != "" {
+		stock, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || stock < 0 {
+			h.logger.Warn("invalid stock value", zap.String("stock", v))
+			http.Error(w, "invalid stock value", http.StatusBadRequest)
 			return
 		}
 	}
 
-	imagePath := filepath.Join(uploadDir, handler.Filename)
-	out, err := os.Create(imagePath)
+	file, _, err := r.FormFile("image")
 	if err != nil {
-		h.logger.Error("creating image file err", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		h.logger.Warn("missing image file", zap.Error(err))
+		http.Error(w, "missing image file", http.StatusBadRequest)
 		return
 	}
-	defer out.Close()
-	_, err = io.Copy(out, file)
+	defer file.Close()
+
+	imageURL, images, err := h.storeImage(r.Context(), file)
 	if err != nil {
-		h.logger.Error("err copying image file", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		code, msg := imageErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			h.logger.Error("err storing image", zap.Error(err))
+		}
+		http.Error(w, msg, code)
 		return
 	}
 	p := &models.Product{
 		Name:        name,
 		Category:    category,
-		Image:       imagePath,
+		CategoryID:  categoryID,
+		Image:       imageURL,
+		Images:      images,
 		PriceCents:  priceCents,
 		Description: description,
+		Gallery: []models.GalleryImage{{
+			ID:      primitive.NewObjectID().Hex(),
+			URL:     imageURL,
+			Images:  images,
+			Alt:     alt,
+			Primary: true,
+		}},
 	}
 
 	createdProduct, err := h.productRepo.CreateProduct(r.Context(), p)
@@ -170,6 +257,22 @@ func (h *ProductHandler) CreateProductHTTP(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "failed to create product", http.StatusInternalServerError)
 		return
 	}
+	h.recordPrice(r.Context(), createdProduct, models.PriceCreated, authResp.UserId, "")
+	h.suggester.Refresh()
+	if stock > 0 {
+		inventory, err := h.inventoryRepo.AdjustStock(r.Context(), createdProduct.ID.Hex(), &models.StockMovement{
+			Delta:   stock,
+			Reason:  models.StockRestock,
+			Note:    "initial stock",
+			ActorID: authResp.UserId,
+		})
+		if err != nil {
+			h.logger.Error("err setting initial stock", zap.Error(err), zap.String("product_id", createdProduct.ID.Hex()))
+			http.Error(w, "product created but setting stock failed", http.StatusInternalServerError)
+			return
+		}
+		createdProduct.Stock = *inventory
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -219,6 +322,7 @@ func (h *ProductHandler) GetProductByIdHTTP(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	setETag(w, product)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(product)
@@ -247,29 +351,43 @@ func (h *ProductHandler) SearchProductHTTP(w http.ResponseWriter, r *http.Reques
 	if !authResp.Valid {
 		h.logger.Warn("invalid token", zap.String("path", r.URL.Path))
 	}
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		h.logger.Warn("missing search query field", zap.String("path", r.URL.Path))
-		http.Error(w, "missing search query", http.StatusBadRequest)
+	query, err := search.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		h.logger.Warn("bad search query", zap.Error(err), zap.String("path", r.URL.Path))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"name": bson.M{"$regex": query, "$options": "i"}},
-			{"category": bson.M{"$regex": query, "$options": "i"}},
-		},
+	filter, err := listFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	products, err := h.productRepo.SearchProduct(r.Context(), filter, 0, 0)
+	res, err := h.searchEngine.Search(r.Context(), query, filter)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		h.logger.Error("couldn't search for products")
+		h.logger.Error("couldn't search for products", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	// later pages are the same search, only the first one counts
+	if filter.Cursor == "" {
+		if err := h.searchLogRepo.LogQuery(r.Context(), strings.Join(query.Terms, " ")); err != nil {
+			h.logger.Warn("err logging search query", zap.Error(err))
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(map[string]any{
+		"products":    res.Products,
+		"next_cursor": res.NextCursor,
+		"total":       res.Total,
+		"fuzzy":       res.Fuzzy,
+	})
 }
 
 func (h *ProductHandler) UpdateProductHTTP(w http.ResponseWriter, r *http.Request) {
@@ -294,6 +412,8 @@ func (h *ProductHandler) UpdateProductHTTP(w http.ResponseWriter, r *http.Reques
 	}
 	if !authResp.Valid {
 		h.logger.Warn("invalid token", zap.String("path", r.URL.Path))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
 	}
 
 	id := r.URL.Query().Get("id")
@@ -302,10 +422,17 @@ func (h *ProductHandler) UpdateProductHTTP(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "missing product ID", http.StatusBadRequest)
 		return
 	}
+	// without If-Match the update is unconditional like before
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
 
 	var req struct {
 		Name        *string `json:"name,omitempty"`
 		Category    *string `json:"category,omitempty"`
+		CategoryID  *string `json:"category_id,omitempty"`
 		Image       *string `json:"image,omitempty"`
 		PriceCents  *int64  `json:"pricecents,omitempty"`
 		Description *string `json:"description,omitempty"`
@@ -323,11 +450,29 @@ func (h *ProductHandler) UpdateProductHTTP(w http.ResponseWriter, r *http.Reques
 	if req.Category != nil {
 		changes["category"] = *req.Category
 	}
+	if req.CategoryID != nil {
+		c, err := h.resolveCategory(r.Context(), *req.CategoryID)
+		if err != nil {
+			h.logger.Warn("invalid category id", zap.String("category_id", *req.CategoryID), zap.Error(err))
+			http.Error(w, "invalid category_id", http.StatusBadRequest)
+			return
+		}
+		changes["category_id"] = *req.CategoryID
+		changes["category"] = c.Name
+	}
 	if req.Image != nil {
 		changes["image"] = *req.Image
+		// the renditions are of the old image, and a plain url replaces the
+		// whole gallery
+		changes["images"] = nil
+		changes["gallery"] = nil
 	}
 	if req.PriceCents != nil {
-		changes["pricecents"] = *req.PriceCents
+		if *req.PriceCents < 0 {
+			http.Error(w, "pricecents can't be negative", http.StatusBadRequest)
+			return
+		}
+		changes["price"] = *req.PriceCents
 	}
 	if req.Description != nil {
 		changes["description"] = *req.Description
@@ -337,27 +482,31 @@ func (h *ProductHandler) UpdateProductHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	updatedProduct, err := h.productRepo.UpdateProduct(r.Context(), id, changes)
-	if err != nil {
+	updatedProduct, err := h.updateProduct(r.Context(), id, changes, expectedVersion)
+	switch {
+	case errors.Is(err, database.ErrVersionMismatch):
+		http.Error(w, "product was changed by someone else, reload it and retry", http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, database.ErrProductNotFound):
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	case err != nil:
 		h.logger.Error("error updating product in db", zap.Error(err), zap.String("path", r.URL.Path))
-		http.Error(w, "internal server error", http.StatusBadRequest)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	event := models.ProductUpdatedEvent{
-		ID:         updatedProduct.ID.Hex(),
-		Name:       updatedProduct.Name,
-		Category:   updatedProduct.Category,
-		Image:      updatedProduct.Image,
-		PriceCents: updatedProduct.PriceCents,
-		UpdatedAt:  updatedProduct.UpdatedAt,
+	if _, ok := changes["price"]; ok {
+		h.recordPrice(r.Context(), updatedProduct, models.PriceManual, authResp.UserId, "")
 	}
+	event := productUpdatedEvent(updatedProduct)
 
 	if err := h.productProducer.PublishProductUpdated(r.Context(), event); err != nil {
 		log.Printf("Failed to publish ProductUpdatedEvent for product %s: %v", updatedProduct.ID.Hex(), err)
 		h.logger.Error("failed to publish product event", zap.String("event-name", event.Name))
 	}
 
+	setETag(w, updatedProduct)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedProduct)
@@ -398,9 +547,15 @@ func (h *ProductHandler) DeleteProductHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.productRepo.DeleteProduct(r.Context(), id); err != nil {
-		h.logger.Warn("product not found", zap.Error(err))
-		http.Error(w, "product not found", http.StatusBadRequest)
+	err = h.deleteProduct(r.Context(), id, authResp.UserId)
+	if errors.Is(err, database.ErrProductNotFound) {
+		h.logger.Warn("product not found", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err deleting product", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
@@ -418,67 +573,287 @@ func (h *ProductHandler) GetProductById(ctx context.Context, req *productpb.GetP
 	if err != nil {
 		return nil, err
 	}
-	return &productpb.GetProductByIdResponse{
-		Product: &productpb.Product{
-			Id:          product.ID.Hex(),
-			Name:        product.Name,
-			Category:    product.Category,
-			Image:       product.Image,
-			Pricecents:  product.PriceCents,
-			Description: product.Description,
-			CreatedAt:   product.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   product.UpdatedAt.Format(time.RFC3339),
-		},
-	}, nil
+	return &productpb.GetProductByIdResponse{Product: productToProto(product)}, nil
 }
 
 func (h *ProductHandler) UpdateProduct(ctx context.Context, req *productpb.UpdateProductRequest) (*productpb.UpdateProductResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing product ID")
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		// no mask, the zero values mean unchanged
+		for path, set := range map[string]bool{
+			"name":        req.Name != "",
+			"category":    req.Category != "",
+			"image":       req.Image != "",
+			"pricecents":  req.Pricecents != 0,
+			"description": req.Description != "",
+		} {
+			if set {
+				paths = append(paths, path)
+			}
+		}
+	}
+
 	changes := map[string]interface{}{}
-	if req.Name != "" {
-		changes["name"] = req.Name
+	for _, path := range paths {
+		switch path {
+		case "name":
+			if req.Name == "" {
+				return nil, status.Error(codes.InvalidArgument, "name can't be empty")
+			}
+			changes["name"] = req.Name
+		case "category":
+			if req.Category == "" {
+				return nil, status.Error(codes.InvalidArgument, "category can't be empty")
+			}
+			changes["category"] = req.Category
+		case "image":
+			changes["image"] = req.Image
+			// same as over http, the renditions and gallery are of the old image
+			changes["images"] = nil
+			changes["gallery"] = nil
+		case "pricecents":
+			if req.Pricecents < 0 {
+				return nil, status.Error(codes.InvalidArgument, "pricecents can't be negative")
+			}
+			changes["price"] = req.Pricecents
+		case "description":
+			changes["description"] = req.Description
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown update_mask path %q", path)
+		}
+	}
+	if len(changes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no fields to update")
 	}
-	if req.Category != "" {
-		changes["category"] = req.Category
+
+	updatedProduct, err := h.updateProduct(ctx, req.Id, changes, req.ExpectedVersion)
+	switch {
+	case errors.Is(err, database.ErrVersionMismatch):
+		return nil, status.Error(codes.FailedPrecondition, "product was changed since expected_version")
+	case errors.Is(err, database.ErrProductNotFound):
+		return nil, status.Error(codes.NotFound, "product not found")
+	case err != nil:
+		h.logger.Error("err updating product in db", zap.Error(err), zap.String("product_id", req.Id))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	if _, ok := changes["price"]; ok {
+		caller, _ := mtls.Identity(ctx)
+		h.recordPrice(ctx, updatedProduct, models.PriceManual, caller, "")
+	}
+	event := productUpdatedEvent(updatedProduct)
+
+	if err := h.productProducer.PublishProductUpdated(ctx, event); err != nil {
+		log.Printf("Failed to publish ProductUpdatedEvent for product %s: %v", updatedProduct.ID.Hex(), err)
+	}
+
+	return &productpb.UpdateProductResponse{Product: productToProto(updatedProduct)}, nil
+}
+
+// CreateProduct is for catalog tooling, it takes an image url instead of an
+// upload.
+func (h *ProductHandler) CreateProduct(ctx context.Context, req *productpb.CreateProductRequest) (*productpb.CreateProductResponse, error) {
+	if req.Name == "" || req.Pricecents <= 0 || (req.Category == "" && req.CategoryId == "") {
+		return nil, status.Error(codes.InvalidArgument, "name, a positive pricecents and category or category_id are required")
+	}
+	category := req.Category
+	if req.CategoryId != "" {
+		c, err := h.resolveCategory(ctx, req.CategoryId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid category_id")
+		}
+		category = c.Name
+	}
+
+	p := &models.Product{
+		Name:        req.Name,
+		SKU:         req.Sku,
+		Category:    category,
+		CategoryID:  req.CategoryId,
+		Image:       req.Image,
+		PriceCents:  req.Pricecents,
+		Description: req.Description,
 	}
 	if req.Image != "" {
-		changes["image"] = req.Image
+		p.Gallery = []models.GalleryImage{{
+			ID:      primitive.NewObjectID().Hex(),
+			URL:     req.Image,
+			Alt:     req.Name,
+			Primary: true,
+		}}
+	}
+	created, err := h.productRepo.CreateProduct(ctx, p)
+	if errors.Is(err, database.ErrSKUTaken) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		h.logger.Error("err creating product in db", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	caller, _ := mtls.Identity(ctx)
+	h.logger.Info("product created", zap.String("product_id", created.ID.Hex()), zap.String("caller", caller))
+	h.recordPrice(ctx, created, models.PriceCreated, caller, "")
+	h.suggester.Refresh()
+	return &productpb.CreateProductResponse{Product: productToProto(created)}, nil
+}
+
+func (h *ProductHandler) DeleteProduct(ctx context.Context, req *productpb.DeleteProductRequest) (*productpb.DeleteProductResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing product ID")
 	}
-	if req.Pricecents != 0 {
-		changes["pricecents"] = req.Pricecents
+	caller, _ := mtls.Identity(ctx)
+	err := h.deleteProduct(ctx, req.Id, caller)
+	if errors.Is(err, database.ErrProductNotFound) {
+		return nil, status.Error(codes.NotFound, "product not found")
 	}
-	if req.Description != "" {
-		changes["description"] = req.Description
+	if err != nil {
+		h.logger.Error("err deleting product", zap.Error(err), zap.String("product_id", req.Id))
+		return nil, status.Error(codes.Internal, "internal server error")
 	}
-	changes["updated_at"] = time.Now()
+	return &productpb.DeleteProductResponse{Success: true}, nil
+}
 
-	updatedProduct, err := h.productRepo.UpdateProduct(ctx, req.Id, changes)
+// BatchGetProducts prices many cart or order lines in one call.
+func (h *ProductHandler) BatchGetProducts(ctx context.Context, req *productpb.BatchGetProductsRequest) (*productpb.BatchGetProductsResponse, error) {
+	if len(req.Ids) > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids", maxPageSize)
+	}
+	products, err := h.productRepo.GetProductsByIds(ctx, req.Ids)
 	if err != nil {
-		return nil, err
+		h.logger.Error("err batch getting products", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	byID := make(map[string]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID.Hex()] = p
 	}
 
-	event := models.ProductUpdatedEvent{
-		ID:         updatedProduct.ID.Hex(),
-		Name:       updatedProduct.Name,
-		Category:   updatedProduct.Category,
-		Image:      updatedProduct.Image,
-		PriceCents: updatedProduct.PriceCents,
-		UpdatedAt:  updatedProduct.UpdatedAt,
+	resp := &productpb.BatchGetProductsResponse{}
+	seen := map[string]bool{}
+	for _, id := range req.Ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if p, ok := byID[id]; ok {
+			resp.Products = append(resp.Products, productToProto(p))
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
 	}
+	return resp, nil
+}
 
-	if err := h.productProducer.PublishProductUpdated(ctx, event); err != nil {
-		log.Printf("Failed to publish ProductUpdatedEvent for product %s: %v", updatedProduct.ID.Hex(), err)
+func (h *ProductHandler) ListProducts(ctx context.Context, req *productpb.ListProductsRequest) (*productpb.ListProductsResponse, error) {
+	filter := database.ProductFilter{
+		Category: req.Category,
+		MinPrice: req.MinPriceCents,
+		MaxPrice: req.MaxPriceCents,
+		Sort:     req.Sort,
+		Desc:     req.Desc,
+		Cursor:   req.Cursor,
+		Limit:    defaultPageSize,
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid limit")
+	}
+	if req.Limit > 0 {
+		filter.Limit = min(req.Limit, maxPageSize)
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, status.Error(codes.InvalidArgument, "min_price_cents is greater than max_price_cents")
 	}
+	switch filter.Sort {
+	case "", "price", "created_at", "name", "rating":
+	default:
+		return nil, status.Error(codes.InvalidArgument, "sort must be one of price, created_at, name, rating")
+	}
+
+	products, next, total, err := h.productRepo.ListProducts(ctx, filter)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		h.logger.Error("err listing products", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &productpb.ListProductsResponse{NextCursor: next, Total: total}
+	for _, p := range products {
+		resp.Products = append(resp.Products, productToProto(p))
+	}
+	return resp, nil
+}
 
-	return &productpb.UpdateProductResponse{
-		Product: &productpb.Product{
-			Id:          updatedProduct.ID.Hex(),
-			Name:        updatedProduct.Name,
-			Category:    updatedProduct.Category,
-			Image:       updatedProduct.Image,
-			Pricecents:  updatedProduct.PriceCents,
-			Description: updatedProduct.Description,
-			CreatedAt:   updatedProduct.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   updatedProduct.UpdatedAt.Format(time.RFC3339),
-		},
-	}, nil
+// deleteProduct soft deletes and tells the carts, actorID is a user or, over
+// grpc, the calling service.
+func (h *ProductHandler) deleteProduct(ctx context.Context, id, actorID string) error {
+	deleted, err := h.productRepo.DeleteProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+	h.logger.Info("product deleted", zap.String("product_id", id), zap.String("actor_id", actorID))
+	event := models.ProductDeletedEvent{ID: id, DeletedAt: *deleted.DeletedAt}
+	if err := h.productProducer.PublishProductDeleted(ctx, event); err != nil {
+		h.logger.Error("failed to publish product deleted event", zap.Error(err), zap.String("product_id", id))
+	}
+	return nil
+}
+
+func productToProto(p *models.Product) *productpb.Product {
+	resp := &productpb.Product{
+		Id:             p.ID.Hex(),
+		Name:           p.Name,
+		Category:       p.Category,
+		Image:          p.Image,
+		Pricecents:     p.PriceCents,
+		Description:    p.Description,
+		Version:        p.Version,
+		CreatedAt:      p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      p.UpdatedAt.Format(time.RFC3339),
+		StockAvailable: p.Available(),
+		RatingAverage:  p.Rating.Average,
+		RatingCount:    p.Rating.Count,
+	}
+	if p.DeletedAt != nil {
+		resp.DeletedAt = p.DeletedAt.Format(time.RFC3339)
+	}
+	for _, opt := range p.Options {
+		resp.Options = append(resp.Options, &productpb.ProductOption{Name: opt.Name, Values: opt.Values})
+	}
+	for _, v := range p.Variants {
+		resp.Variants = append(resp.Variants, &productpb.Variant{
+			Id:             v.ID,
+			Sku:            v.SKU,
+			Options:        v.Options,
+			Pricecents:     v.PriceCents,
+			Image:          v.Image,
+			StockAvailable: v.Stock.Available(),
+		})
+	}
+	return resp
+}
+
+func productUpdatedEvent(p *models.Product) models.ProductUpdatedEvent {
+	event := models.ProductUpdatedEvent{
+		ID:         p.ID.Hex(),
+		Name:       p.Name,
+		Category:   p.Category,
+		Image:      p.Image,
+		PriceCents: p.PriceCents,
+		UpdatedAt:  p.UpdatedAt,
+	}
+	for _, v := range p.Variants {
+		event.Variants = append(event.Variants, models.VariantPrice{
+			ID:         v.ID,
+			SKU:        v.SKU,
+			Image:      v.Image,
+			PriceCents: v.PriceCents,
+		})
+	}
+	return event
 }
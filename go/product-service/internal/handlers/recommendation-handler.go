@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRecommendations = 8
+	// products of the category scored for related, best rated first
+	relatedCandidates = 200
+	// orders looked at for bought together
+	boughtTogetherWindow = 90 * 24 * time.Hour
+	// pairs seen less often are noise
+	boughtTogetherMinOrders  = 2
+	boughtTogetherPerProduct = 20
+)
+
+// RecommendationsHTTP returns products related to the given one by category
+// and attributes, and the ones most often bought with it.
+func (h *ProductHandler) RecommendationsHTTP(w http.ResponseWriter, r *http.Request) {
+	limit := int64(defaultRecommendations)
+	if r.URL.Query().Get("limit") != "" {
+		var err error
+		if limit, err = pageLimit(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, boughtTogetherPerProduct)
+	}
+
+	id := r.PathValue("id")
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err != nil || product.ID.IsZero() || product.DeletedAt != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+
+	related, err := h.relatedProducts(r.Context(), product, int(limit))
+	if err != nil {
+		h.logger.Error("err finding related products", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	together, err := h.boughtTogether(r.Context(), id, int(limit))
+	if err != nil {
+		h.logger.Error("err fetching bought together", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"related":         related,
+		"bought_together": together,
+	})
+}
+
+// relatedProducts ranks the other products of the category by how much they
+// look like p, see relatedScore.
+func (h *ProductHandler) relatedProducts(ctx context.Context, p *models.Product, limit int) ([]*models.Product, error) {
+	filter := database.ProductFilter{
+		Match: bson.M{"_id": bson.M{"$ne": p.ID}},
+		Sort:  "rating",
+		Desc:  true,
+		Limit: relatedCandidates,
+	}
+	switch {
+	case p.CategoryID != "":
+		filter.Match["category_id"] = p.CategoryID
+	case p.Category != "":
+		filter.Category = p.Category
+	default:
+		return []*models.Product{}, nil
+	}
+	candidates, _, _, err := h.productRepo.ListProducts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[*models.Product]float64, len(candidates))
+	for _, c := range candidates {
+		scores[c] = relatedScore(p, c)
+	}
+	// stable keeps the rating order on ties
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i]] > scores[candidates[j]]
+	})
+	return candidates[:min(limit, len(candidates))], nil
+}
+
+// relatedScore weighs shared name words most, then shared option values
+// (same color, same size range) and how close the prices are.
+func relatedScore(p, other *models.Product) float64 {
+	return 2*jaccard(models.Tokenize(p.Name), models.Tokenize(other.Name)) +
+		jaccard(optionValues(p), optionValues(other)) +
+		priceCloseness(p.PriceCents, other.PriceCents)
+}
+
+func optionValues(p *models.Product) []string {
+	var values []string
+	for _, o := range p.Options {
+		name := strings.ToLower(o.Name)
+		for _, v := range o.Values {
+			values = append(values, name+"="+strings.ToLower(v))
+		}
+	}
+	return values
+}
+
+// jaccard is the share of distinct words the two have in common.
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := map[string]bool{}
+	for _, w := range a {
+		set[w] = true
+	}
+	shared, union := 0, len(set)
+	seen := map[string]bool{}
+	for _, w := range b {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
+
+// priceCloseness is 1 for the same price down to 0 when one is free.
+func priceCloseness(a, b int64) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	return float64(min(a, b)) / float64(max(a, b))
+}
+
+// boughtTogether resolves the precomputed list, skipping products deleted
+// since the last run.
+func (h *ProductHandler) boughtTogether(ctx context.Context, id string, limit int) ([]*models.Product, error) {
+	list, err := h.recsRepo.BoughtTogether(ctx, id)
+	if err != nil || list == nil {
+		return []*models.Product{}, err
+	}
+	ids := make([]string, len(list.Products))
+	for i, c := range list.Products {
+		ids[i] = c.ProductID
+	}
+	found, err := h.productRepo.GetProductsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Product, len(found))
+	for _, p := range found {
+		byID[p.ID.Hex()] = p
+	}
+
+	products := []*models.Product{}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok && p.DeletedAt == nil {
+			products = append(products, p)
+			if len(products) == limit {
+				break
+			}
+		}
+	}
+	return products, nil
+}
+
+// RunRecommendations rebuilds the bought together lists now and then every
+// interval until ctx is done. Every instance may run it, a run replaces the
+// lists wholesale.
+func (h *ProductHandler) RunRecommendations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		n, err := h.recsRepo.ComputeBoughtTogether(ctx, start.Add(-boughtTogetherWindow), boughtTogetherMinOrders, boughtTogetherPerProduct)
+		if err != nil {
+			h.logger.Error("err computing bought together", zap.Error(err))
+		} else {
+			h.logger.Info("bought together computed", zap.Int64("products", n), zap.Duration("took", time.Since(start)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
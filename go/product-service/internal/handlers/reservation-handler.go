@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"grpc_module/product/productpb"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultReservationTTL = 15 * time.Minute
+	maxReservationTTL     = time.Hour
+	maxReservationLines   = 100
+	sweepBatchSize        = 100
+	// a commit or release still settling after this stopped halfway
+	settleGrace = time.Minute
+)
+
+func (h *ProductHandler) ReserveStock(ctx context.Context, req *productpb.ReserveStockRequest) (*productpb.ReserveStockResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing order id")
+	}
+	if len(req.Lines) == 0 || len(req.Lines) > maxReservationLines {
+		return nil, status.Errorf(codes.InvalidArgument, "between 1 and %d lines required", maxReservationLines)
+	}
+	ttl := defaultReservationTTL
+	if req.TtlSeconds > 0 {
+		ttl = min(time.Duration(req.TtlSeconds)*time.Second, maxReservationTTL)
+	}
+
+	// same product and variant on several lines is reserved as one
+	var lines []models.ReservationLine
+	index := map[string]int{}
+	for _, line := range req.Lines {
+		if line.ProductId == "" || line.Quantity <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "every line needs a product id and a positive quantity")
+		}
+		key := line.ProductId + "/" + line.VariantId
+		if i, ok := index[key]; ok {
+			lines[i].Quantity += line.Quantity
+			continue
+		}
+		index[key] = len(lines)
+		lines = append(lines, models.ReservationLine{ProductID: line.ProductId, VariantID: line.VariantId, Quantity: line.Quantity})
+	}
+
+	reservation, levels, err := h.inventoryRepo.ReserveStock(ctx, req.OrderId, lines, ttl)
+	switch {
+	case errors.Is(err, database.ErrInsufficientStock), errors.Is(err, database.ErrVariantRequired):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, database.ErrProductNotFound), errors.Is(err, database.ErrVariantNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, database.ErrStockContention):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, database.ErrReservationNotActive):
+		return nil, status.Errorf(codes.FailedPrecondition, "reservation for order %s is already %s", req.OrderId, reservation.Status)
+	case err != nil:
+		h.logger.Error("db error reserving stock", zap.Error(err), zap.String("order_id", req.OrderId))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	for i, stock := range levels {
+		if stock != nil {
+			line := reservation.Lines[i]
+			h.publishStockChanged(ctx, line.ProductID, line.VariantID, 0, models.StockReserved, stock, stock.Available()+line.Quantity)
+		}
+	}
+	h.logger.Info("stock reserved", zap.String("order_id", req.OrderId), zap.Time("expires_at", reservation.ExpiresAt))
+	return &productpb.ReserveStockResponse{
+		ReservationId: reservation.ID.Hex(),
+		ExpiresAt:     reservation.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (h *ProductHandler) CommitReservation(ctx context.Context, req *productpb.CommitReservationRequest) (*productpb.CommitReservationResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing order id")
+	}
+	if err := h.CommitOrder(ctx, req.OrderId); err != nil {
+		return nil, reservationStatus(err)
+	}
+	return &productpb.CommitReservationResponse{Success: true}, nil
+}
+
+func (h *ProductHandler) ReleaseReservation(ctx context.Context, req *productpb.ReleaseReservationRequest) (*productpb.ReleaseReservationResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing order id")
+	}
+	if err := h.ReleaseOrder(ctx, req.OrderId, req.Reason); err != nil {
+		return nil, reservationStatus(err)
+	}
+	return &productpb.ReleaseReservationResponse{Success: true}, nil
+}
+
+func reservationStatus(err error) error {
+	switch {
+	case errors.Is(err, database.ErrReservationNotFound):
+		return status.Error(codes.NotFound, "reservation not found")
+	case errors.Is(err, database.ErrReservationNotActive):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// CommitOrder is shared by the RPC and the PaymentCaptured consumer.
+func (h *ProductHandler) CommitOrder(ctx context.Context, orderID string) error {
+	reservation, levels, err := h.inventoryRepo.CommitReservation(ctx, orderID)
+	if err != nil {
+		if !errors.Is(err, database.ErrReservationNotFound) {
+			h.logger.Error("err committing reservation", zap.Error(err), zap.String("order_id", orderID))
+		}
+		return err
+	}
+	for i, stock := range levels {
+		if stock != nil {
+			line := reservation.Lines[i]
+			h.publishStockChanged(ctx, line.ProductID, line.VariantID, -line.Quantity, models.StockSold, stock, stock.Available())
+		}
+	}
+	h.logger.Info("reservation committed", zap.String("order_id", orderID))
+	return nil
+}
+
+// ReleaseOrder is shared by the RPC and the PaymentFailed consumer.
+func (h *ProductHandler) ReleaseOrder(ctx context.Context, orderID, reason string) error {
+	return h.release(ctx, orderID, models.ReservationReleased, reason)
+}
+
+func (h *ProductHandler) release(ctx context.Context, orderID string, to models.ReservationStatus, reason string) error {
+	reservation, levels, err := h.inventoryRepo.ReleaseReservation(ctx, orderID, to, reason)
+	if err != nil {
+		if !errors.Is(err, database.ErrReservationNotFound) {
+			h.logger.Error("err releasing reservation", zap.Error(err), zap.String("order_id", orderID))
+		}
+		return err
+	}
+	for i, stock := range levels {
+		if stock != nil {
+			line := reservation.Lines[i]
+			h.publishStockChanged(ctx, line.ProductID, line.VariantID, 0, models.StockReleased, stock, stock.Available()-line.Quantity)
+		}
+	}
+	h.logger.Info("reservation released",
+		zap.String("order_id", orderID),
+		zap.String("status", string(to)),
+		zap.String("reason", reason),
+	)
+	return nil
+}
+
+// SweepReservations expires reservations whose checkout never finished and
+// finishes commits and releases that stopped halfway, until ctx is done.
+func (h *ProductHandler) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := h.inventoryRepo.ExpiredReservations(ctx, time.Now(), sweepBatchSize)
+			if err != nil {
+				h.logger.Error("err fetching expired reservations", zap.Error(err))
+				continue
+			}
+			for _, reservation := range expired {
+				h.release(ctx, reservation.OrderID, models.ReservationExpired, "expired")
+			}
+
+			unsettled, err := h.inventoryRepo.UnsettledReservations(ctx, time.Now().Add(-settleGrace), sweepBatchSize)
+			if err != nil {
+				h.logger.Error("err fetching unsettled reservations", zap.Error(err))
+				continue
+			}
+			for _, reservation := range unsettled {
+				h.logger.Warn("finishing unsettled reservation", zap.String("order_id", reservation.OrderID), zap.String("status", string(reservation.Status)))
+				if reservation.Status == models.ReservationCommitted {
+					h.CommitOrder(ctx, reservation.OrderID)
+				} else {
+					h.release(ctx, reservation.OrderID, reservation.Status, reservation.Reason)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type reviewRequest struct {
+	Rating int    `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// ListReviewsHTTP is the public, paginated list of approved reviews of a
+// product, newest first, along with its rating.
+func (h *ProductHandler) ListReviewsHTTP(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.reviewedProduct(w, r)
+	if !ok {
+		return
+	}
+	limit, err := pageLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, next, err := h.reviewRepo.ListApproved(r.Context(), product.ID.Hex(), r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("err listing reviews", zap.Error(err), zap.String("product_id", product.ID.Hex()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"reviews":     reviews,
+		"next_cursor": next,
+		"rating":      product.Rating,
+	})
+}
+
+// CreateReviewHTTP adds the caller's review, it stays hidden until approved.
+func (h *ProductHandler) CreateReviewHTTP(w http.ResponseWriter, r *http.Request) {
+	authResp, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+	product, ok := h.reviewedProduct(w, r)
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	review := &models.Review{
+		ProductID: product.ID.Hex(),
+		UserID:    authResp.UserId,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+		Status:    models.ReviewPending,
+	}
+	if err := review.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewRepo.CreateReview(r.Context(), review); err != nil {
+		if errors.Is(err, database.ErrAlreadyReviewed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.logger.Error("err creating review", zap.Error(err), zap.String("product_id", review.ProductID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("review created", zap.String("review_id", review.ID.Hex()), zap.String("user_id", review.UserID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// UpdateMyReviewHTTP edits the caller's review. Edited reviews go back to
+// the moderation queue, so an approved one drops out of the rating until then.
+func (h *ProductHandler) UpdateMyReviewHTTP(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.myReview(w, r)
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	wasApproved := review.Status == models.ReviewApproved
+	review.Rating = req.Rating
+	review.Title = req.Title
+	review.Body = req.Body
+	review.Status = models.ReviewPending
+	review.ModerationNote = ""
+	if err := review.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewRepo.UpdateReview(r.Context(), review); err != nil {
+		h.logger.Error("err updating review", zap.Error(err), zap.String("review_id", review.ID.Hex()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wasApproved {
+		h.publishReviewChanged(r.Context(), review)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+func (h *ProductHandler) DeleteMyReviewHTTP(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.myReview(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewRepo.DeleteReview(r.Context(), review.ID.Hex()); err != nil {
+		h.logger.Error("err deleting review", zap.Error(err), zap.String("review_id", review.ID.Hex()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if review.Status == models.ReviewApproved {
+		h.publishReviewChanged(r.Context(), review)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListReviewQueueHTTP is the moderation queue, ?status= defaults to pending.
+func (h *ProductHandler) ListReviewQueueHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r); !ok {
+		return
+	}
+	reviewStatus := models.ReviewStatus(r.URL.Query().Get("status"))
+	switch reviewStatus {
+	case "":
+		reviewStatus = models.ReviewPending
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+	default:
+		http.Error(w, "status must be pending, approved or rejected", http.StatusBadRequest)
+		return
+	}
+	limit, err := pageLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, err := h.reviewRepo.ListByStatus(r.Context(), reviewStatus, limit)
+	if err != nil {
+		h.logger.Error("err listing reviews", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ModerateReviewHTTP approves or rejects a review, the note is shown to its author.
+func (h *ProductHandler) ModerateReviewHTTP(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status models.ReviewStatus `json:"status"`
+		Note   string              `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Status != models.ReviewApproved && req.Status != models.ReviewRejected {
+		http.Error(w, "status must be approved or rejected", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewRepo.GetReview(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid review id", http.StatusBadRequest)
+		return
+	}
+	if review == nil {
+		http.Error(w, "review not found", http.StatusNotFound)
+		return
+	}
+
+	review.Status = req.Status
+	review.ModerationNote = req.Note
+	review.ModeratedBy = actorID
+	review.ModeratedAt = time.Now()
+	if err := h.reviewRepo.UpdateReview(r.Context(), review); err != nil {
+		h.logger.Error("err moderating review", zap.Error(err), zap.String("review_id", review.ID.Hex()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.publishReviewChanged(r.Context(), review)
+
+	h.logger.Info("review moderated",
+		zap.String("review_id", review.ID.Hex()),
+		zap.String("status", string(review.Status)),
+		zap.String("actor_id", actorID),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+func (h *ProductHandler) reviewedProduct(w http.ResponseWriter, r *http.Request) (*models.Product, bool) {
+	id := r.PathValue("id")
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err != nil {
+		h.logger.Warn("product not found", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "product not found", http.StatusNotFound)
+		return nil, false
+	}
+	// a deleted product is gone for reviews too, listing and writing
+	if product == nil || product.DeletedAt != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return nil, false
+	}
+	return product, true
+}
+
+func (h *ProductHandler) myReview(w http.ResponseWriter, r *http.Request) (*models.Review, bool) {
+	authResp, ok := h.authUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	review, err := h.reviewRepo.GetUserReview(r.Context(), r.PathValue("id"), authResp.UserId)
+	if err != nil {
+		h.logger.Error("err fetching review", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if review == nil {
+		http.Error(w, "review not found", http.StatusNotFound)
+		return nil, false
+	}
+	return review, true
+}
+
+// publishReviewChanged tells the review consumer to recompute the product
+// rating. Only changes to the approved set matter.
+func (h *ProductHandler) publishReviewChanged(ctx context.Context, review *models.Review) {
+	event := models.ReviewChangedEvent{
+		ReviewID:  review.ID.Hex(),
+		ProductID: review.ProductID,
+		Status:    review.Status,
+		Time:      time.Now(),
+	}
+	if err := h.productProducer.PublishReviewChanged(ctx, event); err != nil {
+		h.logger.Error("failed to publish review changed", zap.Error(err), zap.String("review_id", event.ReviewID))
+	}
+}
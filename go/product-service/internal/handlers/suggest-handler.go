@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"product-service/internal/search"
+	"strconv"
+)
+
+// typing past this is a search, not a prefix
+const maxSuggestPrefix = 100
+
+// SuggestHTTP completes ?q= to product names and categories as the user types.
+// It is served from memory, nothing here touches the db.
+func (h *ProductHandler) SuggestHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if len(q) > maxSuggestPrefix {
+		http.Error(w, "q is too long", http.StatusBadRequest)
+		return
+	}
+	limit := search.MaxSuggestions
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, search.MaxSuggestions)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// the trie changes at most every few seconds
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	json.NewEncoder(w).Encode(map[string]any{
+		"suggestions": h.suggester.Suggest(q, limit),
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"product-service/internal/database"
+	"product-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// SetVariantsHTTP replaces the option axes and variants of a product. Variants
+// sent with an existing id keep their stock, new ones start at 0 and get stock
+// through /products/stock/adjust. An empty list removes the variants. The
+// first variants can only be added to a product without stock of its own.
+// If-Match with the product's etag refuses the write once it changed.
+func (h *ProductHandler) SetVariantsHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		h.logger.Warn("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actorID, ok := h.authAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing product id", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Options  []models.ProductOption `json:"options"`
+		Variants []models.Variant       `json:"variants"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err decoding request body", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateVariants(req.Options, req.Variants); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.productRepo.GetProductById(r.Context(), id)
+	if err == nil && product.DeletedAt != nil {
+		err = database.ErrProductNotFound
+	}
+	if err != nil {
+		h.logger.Warn("product not found", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, product) {
+		return
+	}
+
+	// lines held on the product itself could never be committed or released
+	// once it has variants, its own stock has to go first
+	if len(product.Variants) == 0 && len(req.Variants) > 0 && (product.Stock.OnHand != 0 || product.Stock.Reserved != 0) {
+		http.Error(w, "product has stock of its own, adjust it to 0 and wait for its reservations to finish before adding variants", http.StatusConflict)
+		return
+	}
+
+	// stock is never taken from the request
+	kept := map[string]bool{}
+	for i := range req.Variants {
+		v := &req.Variants[i]
+		v.Stock = models.Inventory{}
+		if existing := product.Variant(v.ID); v.ID != "" && existing != nil {
+			v.Stock = existing.Stock
+			kept[v.ID] = true
+			continue
+		}
+		v.ID = primitive.NewObjectID().Hex()
+	}
+	for _, v := range product.Variants {
+		if !kept[v.ID] && v.Stock.Reserved > 0 {
+			http.Error(w, "variant "+v.SKU+" has reserved stock and can't be removed yet", http.StatusConflict)
+			return
+		}
+	}
+
+	updated, err := h.productRepo.SetVariants(r.Context(), id, req.Options, req.Variants, product.Version)
+	switch {
+	case errors.Is(err, database.ErrProductChanged):
+		productChanged(w, r)
+		return
+	case errors.Is(err, database.ErrSKUTaken):
+		http.Error(w, "a sku is already used by another product", http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("err saving variants", zap.Error(err), zap.String("product_id", id))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("variants updated",
+		zap.String("product_id", id),
+		zap.String("actor_id", actorID),
+		zap.Int("variants", len(updated.Variants)),
+	)
+	if err := h.productProducer.PublishProductUpdated(r.Context(), productUpdatedEvent(updated)); err != nil {
+		h.logger.Error("failed to publish product event", zap.Error(err), zap.String("product_id", id))
+	}
+
+	setETag(w, updated)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,120 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	// decoders for the upload formats storage accepts
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size is one rendition, images are scaled down to fit MaxDim on their
+// longest side and never scaled up. Sizes goes from small to large.
+type Size struct {
+	Name   string
+	MaxDim int
+}
+
+var Sizes = []Size{
+	{Name: "thumbnail", MaxDim: 160},
+	{Name: "card", MaxDim: 480},
+	{Name: "detail", MaxDim: 1200},
+}
+
+// decompression bombs are small files with huge dimensions
+const maxPixels = 40_000_000
+
+const jpegQuality = 82
+
+var (
+	ErrTooManyPixels = errors.New("image dimensions are too large")
+	ErrInvalidImage  = errors.New("image can't be decoded")
+)
+
+// Rendition is one encoded size of an upload. Nothing from the original file
+// but the pixels makes it in here, so EXIF and other metadata are gone.
+type Rendition struct {
+	Size   string
+	Width  int
+	Height int
+	JPEG   []byte
+	WebP   []byte
+}
+
+// Process decodes an upload, applies its EXIF orientation and renders every
+// size in JPEG and WebP.
+func Process(data []byte) ([]Rendition, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if format == "jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+
+	// largest first, each size is scaled from the previous one instead of the
+	// full upload
+	renditions := make([]Rendition, 0, len(Sizes))
+	for i := len(Sizes) - 1; i >= 0; i-- {
+		size := Sizes[i]
+		img := resize(src, size.MaxDim)
+		src = img
+
+		var jpg, webp bytes.Buffer
+		if err := jpeg.Encode(&jpg, flatten(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("encoding %s jpeg: %w", size.Name, err)
+		}
+		// lossless, there is no lossy encoder without cgo and the service is
+		// built with CGO_ENABLED=0
+		if err := nativewebp.Encode(&webp, img, nil); err != nil {
+			return nil, fmt.Errorf("encoding %s webp: %w", size.Name, err)
+		}
+		renditions = append(renditions, Rendition{
+			Size:   size.Name,
+			Width:  img.Bounds().Dx(),
+			Height: img.Bounds().Dy(),
+			JPEG:   jpg.Bytes(),
+			WebP:   webp.Bytes(),
+		})
+	}
+	return renditions, nil
+}
+
+func resize(src image.Image, maxDim int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxDim || h > maxDim {
+		if w >= h {
+			w, h = maxDim, max(1, h*maxDim/w)
+		} else {
+			w, h = max(1, w*maxDim/h), maxDim
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// flatten puts transparent images on white, jpeg has no alpha and would turn
+// transparent pixels black.
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Invalidator drops cached products, see cache.Products.
+type Invalidator interface {
+	Invalidate(ctx context.Context, ids ...string)
+}
+
+// CacheConsumer drops products from this replica's cache when any replica
+// changes them. Every replica needs its own group id to see every event.
+type CacheConsumer struct {
+	reader *kafka.Reader
+	cache  Invalidator
+}
+
+// events that change the cached product, all keyed by product id
+var cacheEvents = map[string]bool{
+	"product updated": true,
+	"product deleted": true,
+	"stock changed":   true,
+	"out of stock":    true,
+	"rating changed":  true,
+}
+
+func NewCacheConsumer(brokers []string, topic, groupID string, cache Invalidator) *CacheConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+		// a fresh replica has an empty cache, older events don't matter
+		StartOffset: kafka.LastOffset,
+	})
+	return &CacheConsumer{
+		reader: reader,
+		cache:  cache,
+	}
+}
+
+func (c *CacheConsumer) Consume(ctx context.Context) error {
+	log.Println("CacheConsumer started ...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("CacheConsumer graceful shutdown")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetching message:", err)
+				continue
+			}
+
+			c.ProcessMessage(ctx, msg)
+			if err := c.reader.CommitMessages(ctx, msg); err != nil {
+				log.Println("Couldn't commit message:", err)
+			}
+		}
+	}
+}
+
+func (c *CacheConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) {
+	eventType := ""
+	for _, h := range msg.Headers {
+		if strings.ToLower(h.Key) == "event" {
+			eventType = strings.ToLower(string(h.Value))
+			break
+		}
+	}
+	if cacheEvents[eventType] && len(msg.Key) > 0 {
+		c.cache.Invalidate(ctx, string(msg.Key))
+	}
+}
+
+func (c *CacheConsumer) Close() error {
+	return c.reader.Close()
+}
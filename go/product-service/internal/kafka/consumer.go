@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"product-service/internal/database"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Reservations is what the payment consumer needs from the product handler.
+type Reservations interface {
+	CommitOrder(ctx context.Context, orderID string) error
+	ReleaseOrder(ctx context.Context, orderID, reason string) error
+}
+
+// a failed payment event is retried with backoff up to this long between tries,
+// committing past it would leave the reservation held until it expires
+const (
+	paymentRetryMin = 500 * time.Millisecond
+	paymentRetryMax = time.Minute
+)
+
+type PaymentConsumer struct {
+	reader       *kafka.Reader
+	reservations Reservations
+}
+
+type PaymentEvent struct {
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+}
+
+func NewPaymentConsumer(brokers []string, topic, groupID string, reservations Reservations) *PaymentConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})
+	return &PaymentConsumer{
+		reader:       reader,
+		reservations: reservations,
+	}
+}
+
+func (c *PaymentConsumer) Consume(ctx context.Context) error {
+	log.Println("PaymentConsumer started ...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("PaymentConsumer graceful shutdown")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetching message:", err)
+				continue
+			}
+
+			if !c.processWithRetry(ctx, msg) {
+				log.Println("PaymentConsumer graceful shutdown")
+				return nil
+			}
+			if err := c.reader.CommitMessages(ctx, msg); err != nil {
+				log.Println("Couldn't commit message:", err)
+			}
+		}
+	}
+}
+
+// processWithRetry keeps processing msg until it succeeds, the messages after
+// it wait. False when ctx is done first, msg then stays uncommitted and is
+// read again after a restart.
+func (c *PaymentConsumer) processWithRetry(ctx context.Context, msg kafka.Message) bool {
+	wait := paymentRetryMin
+	for {
+		err := c.ProcessMessage(ctx, msg)
+		if err == nil {
+			return true
+		}
+		log.Printf("[PaymentConsumer] processing offset %d, retrying in %s: %v", msg.Offset, wait, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, paymentRetryMax)
+	}
+}
+
+// ProcessMessage commits the order's reservation once paid and releases it on
+// failure. Orders without a reservation are skipped.
+func (c *PaymentConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) error {
+	eventType := ""
+	for _, h := range msg.Headers {
+		if strings.ToLower(h.Key) == "event" {
+			eventType = string(h.Value)
+			break
+		}
+	}
+	if eventType != "PaymentCaptured" && eventType != "PaymentFailed" {
+		return nil
+	}
+
+	var event PaymentEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		log.Printf("[PaymentConsumer] dropping bad %s event: %v", eventType, err)
+		return nil
+	}
+
+	var err error
+	if eventType == "PaymentCaptured" {
+		err = c.reservations.CommitOrder(ctx, event.OrderID)
+	} else {
+		err = c.reservations.ReleaseOrder(ctx, event.OrderID, "payment failed: "+event.Reason)
+	}
+	if errors.Is(err, database.ErrReservationNotFound) {
+		return nil
+	}
+	if errors.Is(err, database.ErrReservationNotActive) {
+		// e.g. paid after the reservation expired, nothing to retry
+		log.Printf("[PaymentConsumer] %s for order %s: %v", eventType, event.OrderID, err)
+		return nil
+	}
+	return err
+}
+
+func (c *PaymentConsumer) Close() error {
+	return c.reader.Close()
+}
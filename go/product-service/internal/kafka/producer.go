@@ -63,7 +63,7 @@ func (p *ProductProducer) Close() error {
 	return p.writer.Close()
 }
 
-func (p *ProductProducer) PublishProductDelted(ctx context.Context, event models.ProductDeletedEvent) error {
+func (p *ProductProducer) PublishProductDeleted(ctx context.Context, event models.ProductDeletedEvent) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal the event: %w", err)
@@ -80,9 +80,102 @@ func (p *ProductProducer) PublishProductDelted(ctx context.Context, event models
 		Time: time.Now(),
 	}
 	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		log.Println("Failed to write produc update event: ", err)
+		log.Println("Failed to write product deleted event: ", err)
 		return err
 	}
 	log.Println("Product deleted successfully: ", event.ID)
 	return nil
 }
+
+func (p *ProductProducer) PublishStockChanged(ctx context.Context, event models.StockChangedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event: %w", err)
+	}
+	msg := kafka.Message{
+		Key:   []byte(event.ProductID),
+		Value: data,
+		Headers: []kafka.Header{
+			{
+				Key:   "event",
+				Value: []byte("stock changed"),
+			},
+		},
+		Time: time.Now(),
+	}
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		log.Println("Failed to write stock changed event: ", err)
+		return err
+	}
+	return nil
+}
+
+func (p *ProductProducer) PublishOutOfStock(ctx context.Context, event models.OutOfStockEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event: %w", err)
+	}
+	msg := kafka.Message{
+		Key:   []byte(event.ProductID),
+		Value: data,
+		Headers: []kafka.Header{
+			{
+				Key:   "event",
+				Value: []byte("out of stock"),
+			},
+		},
+		Time: time.Now(),
+	}
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		log.Println("Failed to write out of stock event: ", err)
+		return err
+	}
+	log.Println("Product out of stock: ", event.ProductID)
+	return nil
+}
+
+func (p *ProductProducer) PublishReviewChanged(ctx context.Context, event models.ReviewChangedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event: %w", err)
+	}
+	msg := kafka.Message{
+		Key:   []byte(event.ProductID),
+		Value: data,
+		Headers: []kafka.Header{
+			{
+				Key:   "event",
+				Value: []byte("review changed"),
+			},
+		},
+		Time: time.Now(),
+	}
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		log.Println("Failed to write review changed event: ", err)
+		return err
+	}
+	return nil
+}
+
+func (p *ProductProducer) PublishRatingChanged(ctx context.Context, event models.RatingChangedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event: %w", err)
+	}
+	msg := kafka.Message{
+		Key:   []byte(event.ProductID),
+		Value: data,
+		Headers: []kafka.Header{
+			{
+				Key:   "event",
+				Value: []byte("rating changed"),
+			},
+		},
+		Time: time.Now(),
+	}
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		log.Println("Failed to write rating changed event: ", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"product-service/internal/database"
+	"product-service/internal/models"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ReviewConsumer keeps the rating on the product in sync with its approved
+// reviews. It reads our own topic, so a lost update is fixed by the next
+// review change of the same product. A new rating is announced so every
+// replica drops the product from its cache.
+type ReviewConsumer struct {
+	reader      *kafka.Reader
+	reviewRepo  database.ReviewRepository
+	productRepo database.ProductRepository
+	producer    *ProductProducer
+}
+
+func NewReviewConsumer(brokers []string, topic, groupID string, reviewRepo database.ReviewRepository, productRepo database.ProductRepository, producer *ProductProducer) *ReviewConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})
+	return &ReviewConsumer{
+		reader:      reader,
+		reviewRepo:  reviewRepo,
+		productRepo: productRepo,
+		producer:    producer,
+	}
+}
+
+func (c *ReviewConsumer) Consume(ctx context.Context) error {
+	log.Println("ReviewConsumer started ...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("ReviewConsumer graceful shutdown")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetching message:", err)
+				continue
+			}
+
+			if err := c.ProcessMessage(ctx, msg); err != nil {
+				log.Println("Error processing message:", err)
+			} else {
+				if err := c.reader.CommitMessages(ctx, msg); err != nil {
+					log.Println("Couldn't commit message:", err)
+				}
+			}
+		}
+	}
+}
+
+// ProcessMessage recomputes the summary from scratch instead of applying a
+// delta, so redelivered or reordered events can't skew it.
+func (c *ReviewConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) error {
+	eventType := ""
+	for _, h := range msg.Headers {
+		if strings.ToLower(h.Key) == "event" {
+			eventType = string(h.Value)
+			break
+		}
+	}
+	if eventType != "review changed" {
+		return nil
+	}
+
+	var event models.ReviewChangedEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		log.Printf("[ReviewConsumer] dropping bad event: %v", err)
+		return nil
+	}
+
+	summary, err := c.reviewRepo.Summary(ctx, event.ProductID)
+	if err != nil {
+		return err
+	}
+	if err := c.productRepo.SetRating(ctx, event.ProductID, summary); err != nil {
+		return err
+	}
+	// the rating is already stored, a lost event leaves other replicas' cached
+	// copy until its ttl
+	if err := c.producer.PublishRatingChanged(ctx, models.RatingChangedEvent{
+		ProductID: event.ProductID,
+		Rating:    summary,
+		Time:      time.Now(),
+	}); err != nil {
+		log.Printf("[ReviewConsumer] publishing rating change of %s: %v", event.ProductID, err)
+	}
+	return nil
+}
+
+func (c *ReviewConsumer) Close() error {
+	return c.reader.Close()
+}
@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Refresher rebuilds derived data on the next occasion, see search.Suggester.
+type Refresher interface {
+	Refresh()
+}
+
+// SuggestConsumer has this replica's autocomplete rebuilt when any replica
+// renames or deletes a product. Every replica needs its own group id.
+type SuggestConsumer struct {
+	reader    *kafka.Reader
+	suggester Refresher
+}
+
+func NewSuggestConsumer(brokers []string, topic, groupID string, suggester Refresher) *SuggestConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+		// the trie is built from the db at startup
+		StartOffset: kafka.LastOffset,
+	})
+	return &SuggestConsumer{
+		reader:    reader,
+		suggester: suggester,
+	}
+}
+
+func (c *SuggestConsumer) Consume(ctx context.Context) error {
+	log.Println("SuggestConsumer started ...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("SuggestConsumer graceful shutdown")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("Error fetching message:", err)
+				continue
+			}
+
+			c.ProcessMessage(msg)
+			if err := c.reader.CommitMessages(ctx, msg); err != nil {
+				log.Println("Couldn't commit message:", err)
+			}
+		}
+	}
+}
+
+func (c *SuggestConsumer) ProcessMessage(msg kafka.Message) {
+	for _, h := range msg.Headers {
+		if strings.ToLower(h.Key) == "event" {
+			switch strings.ToLower(string(h.Value)) {
+			case "product updated", "product deleted":
+				c.suggester.Refresh()
+			}
+			return
+		}
+	}
+}
+
+func (c *SuggestConsumer) Close() error {
+	return c.reader.Close()
+}
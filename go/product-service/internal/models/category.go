@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Category is a node in the taxonomy. Ancestors is the path from the root,
+// kept on every node so a subtree is one query.
+type Category struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Slug      string             `bson:"slug" json:"slug"`
+	ParentID  string             `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Ancestors []string           `bson:"ancestors" json:"ancestors"`
+	Position  int                `bson:"position" json:"position"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CategoryNode struct {
+	*Category
+	Children []*CategoryNode `json:"children"`
+}
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugStrip   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Slugify turns a name into a slug, "Men's Shoes" -> "men-s-shoes".
+func Slugify(name string) string {
+	return strings.Trim(slugStrip.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func (c *Category) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("name is required")
+	}
+	if !slugPattern.MatchString(c.Slug) {
+		return errors.New("slug must be lowercase letters, digits and dashes")
+	}
+	if c.Position < 0 {
+		return errors.New("position can't be negative")
+	}
+	return nil
+}
+
+// CategoryTree nests categories under their parents, siblings keep the
+// order they come in.
+func CategoryTree(categories []*Category) []*CategoryNode {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID.Hex()] = &CategoryNode{Category: c, Children: []*CategoryNode{}}
+	}
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID.Hex()]
+		if parent, ok := nodes[c.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
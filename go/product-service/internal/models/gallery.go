@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	maxGalleryImages = 20
+	maxAltLength     = 250
+)
+
+// GalleryImage is one picture of a product, the gallery is shown in slice
+// order. Exactly one is primary and Product.Image / Images mirror it, so
+// everything that only knows a single image (cart, cards) keeps working.
+type GalleryImage struct {
+	ID      string                  `bson:"id" json:"id"`
+	URL     string                  `bson:"url" json:"url"`
+	Images  map[string]ImageVariant `bson:"images,omitempty" json:"images,omitempty"`
+	Alt     string                  `bson:"alt" json:"alt"`
+	Primary bool                    `bson:"primary" json:"primary"`
+}
+
+func (p *Product) GalleryImage(id string) *GalleryImage {
+	for i := range p.Gallery {
+		if p.Gallery[i].ID == id {
+			return &p.Gallery[i]
+		}
+	}
+	return nil
+}
+
+// SetPrimary marks id as the primary image, false if it isn't in the gallery.
+func (p *Product) SetPrimary(id string) bool {
+	if p.GalleryImage(id) == nil {
+		return false
+	}
+	for i := range p.Gallery {
+		p.Gallery[i].Primary = p.Gallery[i].ID == id
+	}
+	return true
+}
+
+// PrimaryImage is the primary gallery entry, nil for an empty gallery.
+func (p *Product) PrimaryImage() *GalleryImage {
+	for i := range p.Gallery {
+		if p.Gallery[i].Primary {
+			return &p.Gallery[i]
+		}
+	}
+	return nil
+}
+
+func ValidateGallery(gallery []GalleryImage) error {
+	if len(gallery) > maxGalleryImages {
+		return fmt.Errorf("at most %d images", maxGalleryImages)
+	}
+	primary := 0
+	for _, img := range gallery {
+		if len(img.Alt) > maxAltLength {
+			return fmt.Errorf("alt text is longer than %d characters", maxAltLength)
+		}
+		if img.Primary {
+			primary++
+		}
+	}
+	if len(gallery) > 0 && primary != 1 {
+		return errors.New("exactly one image must be primary")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package models
+
+// ImageVariant is one size of the product image, same pixels in both formats.
+type ImageVariant struct {
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	WebP   string `bson:"webp" json:"webp"`
+	JPEG   string `bson:"jpeg" json:"jpeg"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImportStatus string
+
+const (
+	ImportRunning ImportStatus = "running"
+	ImportDone    ImportStatus = "done"
+	ImportFailed  ImportStatus = "failed"
+)
+
+// only the first ones are kept, enough to fix the file and retry
+const MaxImportErrors = 500
+
+// ImportJob is a bulk import running in the background, polled for progress.
+type ImportJob struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Format    string             `bson:"format" json:"format"`
+	Status    ImportStatus       `bson:"status" json:"status"`
+	Total     int                `bson:"total" json:"total"`
+	Processed int                `bson:"processed" json:"processed"`
+	Created   int                `bson:"created" json:"created"`
+	Updated   int                `bson:"updated" json:"updated"`
+	Failed    int                `bson:"failed" json:"failed"`
+	Errors    []ImportRowError   `bson:"errors" json:"errors"`
+	// set when the job as a whole failed
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	ActorID    string    `bson:"actor_id" json:"actor_id"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	FinishedAt time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// ImportRowError is why a line of the input was skipped.
+type ImportRowError struct {
+	Line  int    `bson:"line" json:"line"`
+	ID    string `bson:"id,omitempty" json:"id,omitempty"`
+	SKU   string `bson:"sku,omitempty" json:"sku,omitempty"`
+	Error string `bson:"error" json:"error"`
+}
+
+func (j *ImportJob) AddError(e ImportRowError) {
+	j.Failed++
+	if len(j.Errors) < MaxImportErrors {
+		j.Errors = append(j.Errors, e)
+	}
+}
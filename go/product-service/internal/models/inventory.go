@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Inventory lives on the product document so stock changes are a single atomic
+// update. Reserved is stock held by checkouts that haven't completed yet.
+type Inventory struct {
+	OnHand   int64 `bson:"on_hand" json:"on_hand"`
+	Reserved int64 `bson:"reserved" json:"reserved"`
+}
+
+func (i Inventory) Available() int64 {
+	return i.OnHand - i.Reserved
+}
+
+func (i Inventory) MarshalJSON() ([]byte, error) {
+	type inventory Inventory
+	return json.Marshal(struct {
+		inventory
+		Available int64 `json:"available"`
+	}{inventory(i), i.Available()})
+}
+
+type StockReason string
+
+const (
+	StockRestock    StockReason = "restock"
+	StockReturn     StockReason = "return"
+	StockDamaged    StockReason = "damaged"
+	StockLost       StockReason = "lost"
+	StockCorrection StockReason = "correction"
+
+	// set by checkouts, not accepted from admins
+	StockReserved StockReason = "reserved"
+	StockReleased StockReason = "released"
+	StockSold     StockReason = "sold"
+)
+
+// Valid is for admin adjustments.
+func (r StockReason) Valid() bool {
+	switch r {
+	case StockRestock, StockReturn, StockDamaged, StockLost, StockCorrection:
+		return true
+	}
+	return false
+}
+
+// StockMovement is one line of the stock ledger, kept for every adjustment.
+type StockMovement struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID string             `bson:"product_id" json:"product_id"`
+	VariantID string             `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Delta     int64              `bson:"delta" json:"delta"`
+	Reason    StockReason        `bson:"reason" json:"reason"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	ActorID   string             `bson:"actor_id" json:"actor_id"`
+	OnHand    int64              `bson:"on_hand" json:"on_hand"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type StockChangedEvent struct {
+	ProductID string      `json:"product_id"`
+	VariantID string      `json:"variant_id,omitempty"`
+	Delta     int64       `json:"delta"`
+	Reason    StockReason `json:"reason"`
+	OnHand    int64       `json:"on_hand"`
+	Reserved  int64       `json:"reserved"`
+	Available int64       `json:"available"`
+	Time      time.Time   `json:"time"`
+}
+type OutOfStockEvent struct {
+	ProductID string    `json:"product_id"`
+	VariantID string    `json:"variant_id,omitempty"`
+	Time      time.Time `json:"time"`
+}
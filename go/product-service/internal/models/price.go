@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PriceReason string
+
+const (
+	PriceCreated   PriceReason = "created"
+	PriceManual    PriceReason = "manual"
+	PriceImport    PriceReason = "import"
+	PriceScheduled PriceReason = "scheduled"
+	PriceSaleStart PriceReason = "sale_start"
+	PriceSaleEnd   PriceReason = "sale_end"
+)
+
+// PriceRecord is one line of the price history, written after every change
+// with the resulting prices. The previous line is what it was before.
+type PriceRecord struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID      string             `bson:"product_id" json:"product_id"`
+	PriceCents     int64              `bson:"price" json:"price"`
+	CompareAtCents int64              `bson:"compare_at_price,omitempty" json:"compare_at_price,omitempty"`
+	Reason         PriceReason        `bson:"reason" json:"reason"`
+	ScheduleID     string             `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`
+	ActorID        string             `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type ScheduleKind string
+
+const (
+	// a new regular price from StartsAt on
+	SchedulePrice ScheduleKind = "price"
+	// a sale price between StartsAt and EndsAt, the regular price comes back after
+	ScheduleSale ScheduleKind = "sale"
+)
+
+type ScheduleStatus string
+
+const (
+	SchedulePending ScheduleStatus = "pending"
+	ScheduleActive  ScheduleStatus = "active" // a running sale
+	// claimed by the scheduler, the price change may or may not have happened
+	ScheduleApplying ScheduleStatus = "applying"
+	ScheduleDone     ScheduleStatus = "done"
+	ScheduleCanceled ScheduleStatus = "canceled"
+	ScheduleFailed   ScheduleStatus = "failed"
+)
+
+// PriceSchedule is a future price change the scheduler applies when due.
+type PriceSchedule struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID  string             `bson:"product_id" json:"product_id"`
+	Kind       ScheduleKind       `bson:"kind" json:"kind"`
+	PriceCents int64              `bson:"price" json:"price"`
+	StartsAt   time.Time          `bson:"starts_at" json:"starts_at"`
+	EndsAt     *time.Time         `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	Status     ScheduleStatus     `bson:"status" json:"status"`
+	// set while applying, a claim older than the timeout is taken over
+	ClaimedAt   *time.Time     `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	ClaimedFrom ScheduleStatus `bson:"claimed_from,omitempty" json:"-"`
+	Error       string         `bson:"error,omitempty" json:"error,omitempty"`
+	ActorID     string         `bson:"actor_id" json:"actor_id"`
+	CreatedAt   time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `bson:"updated_at" json:"updated_at"`
+}
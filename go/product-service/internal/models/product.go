@@ -9,22 +9,58 @@ import (
 type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
+	SKU         string             `bson:"sku,omitempty" json:"sku,omitempty"` // products with variants have sku per variant instead
 	Category    string             `bson:"category" json:"category"`
+	CategoryID  string             `bson:"category_id,omitempty" json:"category_id,omitempty"`
 	Image       string             `bson:"image" json:"image"`
 	PriceCents  int64              `bson:"price" json:"price"`
 	Description string             `bson:"description" json:"description"`
+	Stock       Inventory          `bson:"stock" json:"stock"`
+	Options     []ProductOption    `bson:"options,omitempty" json:"options,omitempty"`
+	Variants    []Variant          `bson:"variants,omitempty" json:"variants,omitempty"`
+	Rating      RatingSummary      `bson:"rating" json:"rating"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// resized renditions of Image by size: thumbnail, card, detail
+	Images map[string]ImageVariant `bson:"images,omitempty" json:"images,omitempty"`
+	// all images in display order, Image and Images are its primary
+	Gallery []GalleryImage `bson:"gallery,omitempty" json:"gallery,omitempty"`
+	// trigrams of name and category for typo tolerant search
+	SearchNgrams []string `bson:"search_ngrams,omitempty" json:"-"`
+	// relevance, only set on search results
+	Score float64 `bson:"score,omitempty" json:"score,omitempty"`
+
+	// set by a delete, the product is hidden from listings but orders can
+	// still resolve it
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// bumped by every catalog edit, not by stock or rating changes, it is the
+	// etag of the product
+	Version int64 `bson:"version" json:"version"`
+
+	// during a sale PriceCents is the sale price and CompareAtCents the
+	// regular one, for was/now display
+	CompareAtCents int64      `bson:"compare_at_price,omitempty" json:"compare_at_price,omitempty"`
+	SaleEndsAt     *time.Time `bson:"sale_ends_at,omitempty" json:"sale_ends_at,omitempty"`
 }
 
 // Kafka Events
 type ProductUpdatedEvent struct {
-	ID         string    `json:"id"`
-	Name       string    `json:"name"`
-	Category   string    `json:"category"`
-	Image      string    `json:"image"`
-	PriceCents int64     `json:"price_cents"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Category   string         `json:"category"`
+	Image      string         `json:"image"`
+	PriceCents int64          `json:"price_cents"`
+	Variants   []VariantPrice `json:"variants,omitempty"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// VariantPrice is what carts denormalize per variant line.
+type VariantPrice struct {
+	ID         string `json:"id"`
+	SKU        string `json:"sku"`
+	Image      string `json:"image,omitempty"`
+	PriceCents int64  `json:"price_cents"`
 }
 type ProductDeletedEvent struct {
 	ID        string    `json:"id"`
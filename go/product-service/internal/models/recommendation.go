@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// BoughtTogether is the precomputed "frequently bought together" list of a
+// product, rebuilt from paid orders by the recommendations job.
+type BoughtTogether struct {
+	ProductID string `bson:"_id" json:"product_id"`
+	// paid orders with the product in the window
+	Orders int64 `bson:"orders" json:"orders"`
+	// most often first
+	Products   []CoPurchase `bson:"products" json:"products"`
+	ComputedAt time.Time    `bson:"computed_at" json:"computed_at"`
+}
+
+// CoPurchase is another product and how many orders had both.
+type CoPurchase struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	Orders    int64  `bson:"orders" json:"orders"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds stock for one order between checkout and payment.
+type Reservation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID   string             `bson:"order_id" json:"order_id"`
+	Lines     []ReservationLine  `bson:"lines" json:"lines"`
+	Status    ReservationStatus  `bson:"status" json:"status"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// set with the move to committed, released or expired until every held
+	// line has been applied to stock, see Settled
+	Settling bool `bson:"settling,omitempty" json:"settling,omitempty"`
+}
+
+// Held is set once the line's stock has actually been reserved on the product,
+// so a reservation interrupted halfway only gives back what it took.
+type ReservationLine struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	VariantID string `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	Quantity  int64  `bson:"quantity" json:"quantity"`
+	Held      bool   `bson:"held" json:"held"`
+	// the hold was sold or given back, a retried commit or release skips it
+	Settled bool `bson:"settled,omitempty" json:"settled,omitempty"`
+}
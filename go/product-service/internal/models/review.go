@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+const maxReviewLength = 5000
+
+// Review is one user's review of a product, only approved ones are public and
+// count towards the product rating. VerifiedPurchase stays false until there is
+// an order service to check purchases against.
+type Review struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID        string             `bson:"product_id" json:"product_id"`
+	UserID           string             `bson:"user_id" json:"user_id"`
+	Rating           int                `bson:"rating" json:"rating"`
+	Title            string             `bson:"title,omitempty" json:"title,omitempty"`
+	Body             string             `bson:"body" json:"body"`
+	VerifiedPurchase bool               `bson:"verified_purchase" json:"verified_purchase"`
+	Status           ReviewStatus       `bson:"status" json:"status"`
+	ModerationNote   string             `bson:"moderation_note,omitempty" json:"moderation_note,omitempty"`
+	ModeratedBy      string             `bson:"moderated_by,omitempty" json:"moderated_by,omitempty"`
+	ModeratedAt      time.Time          `bson:"moderated_at,omitempty" json:"moderated_at,omitempty"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (r *Review) Validate() error {
+	if r.Rating < 1 || r.Rating > 5 {
+		return errors.New("rating must be between 1 and 5")
+	}
+	if strings.TrimSpace(r.Body) == "" {
+		return errors.New("review text is required")
+	}
+	if len(r.Body) > maxReviewLength || len(r.Title) > 200 {
+		return errors.New("review is too long")
+	}
+	return nil
+}
+
+// RatingSummary is denormalized onto the product from its approved reviews.
+type RatingSummary struct {
+	Average float64 `bson:"average" json:"average"`
+	Count   int64   `bson:"count" json:"count"`
+}
+
+type ReviewChangedEvent struct {
+	ReviewID  string       `json:"review_id"`
+	ProductID string       `json:"product_id"`
+	Status    ReviewStatus `json:"status"`
+	Time      time.Time    `json:"time"`
+}
+
+// RatingChangedEvent follows a new rating summary onto the product.
+type RatingChangedEvent struct {
+	ProductID string        `json:"product_id"`
+	Rating    RatingSummary `json:"rating"`
+	Time      time.Time     `json:"time"`
+}
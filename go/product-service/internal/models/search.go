@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize lowercases s and splits it into words of letters and digits,
+// everything else is a separator.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchNgrams returns the distinct trigrams of every word in fields. Words are
+// padded so "tv" still gives "$tv" and "tv$".
+func SearchNgrams(fields ...string) []string {
+	seen := map[string]bool{}
+	ngrams := []string{}
+	for _, field := range fields {
+		for _, word := range Tokenize(field) {
+			padded := []rune("$" + word + "$")
+			for i := 0; i+3 <= len(padded); i++ {
+				gram := string(padded[i : i+3])
+				if !seen[gram] {
+					seen[gram] = true
+					ngrams = append(ngrams, gram)
+				}
+			}
+		}
+	}
+	return ngrams
+}
+
+// QueryCount is how often a normalized search query was run.
+type QueryCount struct {
+	Query string `bson:"_id" json:"query"`
+	Count int64  `bson:"count" json:"count"`
+}
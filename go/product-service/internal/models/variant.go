@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	maxOptionAxes = 3
+	maxVariants   = 100
+)
+
+// ProductOption is one axis variants differ on, e.g. size with S, M, L.
+type ProductOption struct {
+	Name   string   `bson:"name" json:"name"`
+	Values []string `bson:"values" json:"values"`
+}
+
+// Variant is a sellable combination of option values. Products with variants
+// keep stock per variant, the product level stock is unused for them.
+type Variant struct {
+	ID         string            `bson:"id" json:"id"`
+	SKU        string            `bson:"sku" json:"sku"`
+	Options    map[string]string `bson:"options" json:"options"`
+	PriceCents int64             `bson:"price" json:"price"`
+	Image      string            `bson:"image,omitempty" json:"image,omitempty"`
+	Stock      Inventory         `bson:"stock" json:"stock"`
+}
+
+func (p *Product) Variant(id string) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// Available is the product's stock, summed over the variants if it has any.
+func (p *Product) Available() int64 {
+	if len(p.Variants) == 0 {
+		return p.Stock.Available()
+	}
+	var total int64
+	for _, v := range p.Variants {
+		total += v.Stock.Available()
+	}
+	return total
+}
+
+// ValidateVariants checks every variant picks exactly one allowed value per
+// axis, and that combinations and SKUs don't repeat.
+func ValidateVariants(options []ProductOption, variants []Variant) error {
+	if len(options) > maxOptionAxes {
+		return fmt.Errorf("at most %d option axes", maxOptionAxes)
+	}
+	if len(variants) > maxVariants {
+		return fmt.Errorf("at most %d variants", maxVariants)
+	}
+	if len(variants) > 0 && len(options) == 0 {
+		return errors.New("variants need at least one option axis")
+	}
+
+	allowed := map[string]map[string]bool{}
+	for _, opt := range options {
+		if opt.Name == "" || len(opt.Values) == 0 {
+			return errors.New("every option needs a name and values")
+		}
+		if allowed[opt.Name] != nil {
+			return fmt.Errorf("duplicate option %q", opt.Name)
+		}
+		allowed[opt.Name] = map[string]bool{}
+		for _, v := range opt.Values {
+			allowed[opt.Name][v] = true
+		}
+	}
+
+	combos := map[string]bool{}
+	skus := map[string]bool{}
+	for _, v := range variants {
+		if strings.TrimSpace(v.SKU) == "" {
+			return errors.New("every variant needs a sku")
+		}
+		if skus[v.SKU] {
+			return fmt.Errorf("duplicate sku %q", v.SKU)
+		}
+		skus[v.SKU] = true
+		if v.PriceCents < 0 {
+			return fmt.Errorf("variant %s has a negative price", v.SKU)
+		}
+		if len(v.Options) != len(options) {
+			return fmt.Errorf("variant %s must set every option", v.SKU)
+		}
+		key := make([]string, 0, len(options))
+		for name, value := range v.Options {
+			if !allowed[name][value] {
+				return fmt.Errorf("variant %s has invalid %s %q", v.SKU, name, value)
+			}
+			key = append(key, name+"="+value)
+		}
+		sort.Strings(key)
+		combo := strings.Join(key, ",")
+		if combos[combo] {
+			return fmt.Errorf("more than one variant for %s", combo)
+		}
+		combos[combo] = true
+	}
+	return nil
+}
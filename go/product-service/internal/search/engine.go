@@ -0,0 +1,198 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"product-service/internal/database"
+	"product-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// share of the query trigrams a product needs before it counts as a fuzzy match
+const minNgramOverlap = 0.3
+
+type SearchEngine interface {
+	// Search ranks by relevance unless filter.Sort is set. Fuzzy is true when
+	// nothing matched the words exactly and the results come from the ngram index.
+	Search(ctx context.Context, q Query, filter database.ProductFilter) (*Result, error)
+}
+
+type Result struct {
+	Products   []*models.Product
+	NextCursor string
+	Total      int64
+	Fuzzy      bool
+}
+
+type mongoEngine struct {
+	col  *mongo.Collection
+	repo database.ProductRepository
+}
+
+func NewMongoEngine(client *mongo.Client, dbName string, repo database.ProductRepository) *mongoEngine {
+	col := client.Database(dbName).Collection("product")
+
+	idxModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "category", Value: "text"},
+				{Key: "description", Value: "text"},
+			},
+			Options: options.Index().SetName("product_text").SetWeights(bson.D{
+				{Key: "name", Value: 10},
+				{Key: "category", Value: 5},
+				{Key: "description", Value: 1},
+			}),
+		},
+		{Keys: bson.D{{Key: "search_ngrams", Value: 1}}},
+	}
+	_, _ = col.Indexes().CreateMany(context.Background(), idxModels)
+
+	return &mongoEngine{col: col, repo: repo}
+}
+
+func (e *mongoEngine) Search(ctx context.Context, q Query, filter database.ProductFilter) (*Result, error) {
+	filter.Match = bson.M{"$text": bson.M{"$search": q.text()}}
+	res, err := e.search(ctx, filter, bson.M{"$meta": "textScore"}, false)
+	if err != nil || res.Total > 0 {
+		return res, err
+	}
+
+	grams := q.ngrams()
+	filter.Match = bson.M{"search_ngrams": bson.M{"$in": grams}}
+	overlap := bson.M{"$divide": bson.A{
+		bson.M{"$size": bson.M{"$setIntersection": bson.A{"$search_ngrams", grams}}},
+		len(grams),
+	}}
+	res, err = e.search(ctx, filter, overlap, true)
+	if err != nil {
+		return nil, err
+	}
+	res.Fuzzy = true
+	return res, nil
+}
+
+func (e *mongoEngine) search(ctx context.Context, filter database.ProductFilter, score any, fuzzy bool) (*Result, error) {
+	if filter.Sort != "" && !fuzzy {
+		products, next, total, err := e.repo.ListProducts(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Products: products, NextCursor: next, Total: total}, nil
+	}
+	return e.ranked(ctx, filter, score, fuzzy)
+}
+
+// ranked pages by score, the cursor is an offset since scores aren't stable
+// enough to seek on.
+func (e *mongoEngine) ranked(ctx context.Context, filter database.ProductFilter, score any, fuzzy bool) (*Result, error) {
+	offset, err := decodeOffset(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.Query()}},
+		{{Key: "$addFields", Value: bson.M{"score": score}}},
+	}
+	if fuzzy {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"score": bson.M{"$gte": minNgramOverlap}}}})
+	}
+	sort := bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}
+	if fuzzy && filter.Sort != "" {
+		// fuzzy hits still honour an explicit sort, score just breaks ties
+		order := 1
+		if filter.Desc {
+			order = -1
+		}
+		sort = append(bson.D{{Key: filter.Sort, Value: order}}, sort...)
+	}
+	items := bson.A{bson.M{"$sort": sort}, bson.M{"$skip": offset}}
+	if filter.Limit > 0 {
+		// one extra to know if there is a next page
+		items = append(items, bson.M{"$limit": filter.Limit + 1})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"items": items,
+		"total": bson.A{bson.M{"$count": "n"}},
+	}}})
+
+	cur, err := e.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var facets []struct {
+		Items []*models.Product `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cur.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Products: []*models.Product{}}
+	if len(facets) == 0 {
+		return res, nil
+	}
+	res.Products = facets[0].Items
+	if len(facets[0].Total) > 0 {
+		res.Total = facets[0].Total[0].N
+	}
+	if filter.Limit > 0 && int64(len(res.Products)) > filter.Limit {
+		res.Products = res.Products[:filter.Limit]
+		res.NextCursor = encodeOffset(offset + filter.Limit)
+	}
+	return res, nil
+}
+
+func encodeOffset(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.FormatInt(offset, 10)))
+}
+
+func decodeOffset(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) < 3 || string(data[:2]) != "o:" {
+		return 0, database.ErrInvalidCursor
+	}
+	offset, err := strconv.ParseInt(string(data[2:]), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, database.ErrInvalidCursor
+	}
+	return offset, nil
+}
+
+// Backfill sets search_ngrams on products created before the field existed.
+func (e *mongoEngine) Backfill(ctx context.Context) (int, error) {
+	cur, err := e.col.Find(ctx, bson.M{"search_ngrams": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	for cur.Next(ctx) {
+		var product models.Product
+		if err := cur.Decode(&product); err != nil {
+			return n, err
+		}
+		grams := models.SearchNgrams(product.Name, product.Category)
+		_, err := e.col.UpdateOne(ctx, bson.M{"_id": product.ID}, bson.M{"$set": bson.M{"search_ngrams": grams}})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, cur.Err()
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"errors"
+	"strings"
+
+	"product-service/internal/models"
+)
+
+const (
+	maxQueryLength = 200
+	maxTerms       = 10
+)
+
+var ErrEmptyQuery = errors.New("search query has no words")
+
+// Query is a parsed search, only plain lowercase words survive so nothing the
+// user types ends up as regex or text search operators (quotes, negation).
+type Query struct {
+	Terms []string
+}
+
+func ParseQuery(raw string) (Query, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxQueryLength {
+		return Query{}, errors.New("search query is too long")
+	}
+
+	var q Query
+	seen := map[string]bool{}
+	for _, term := range models.Tokenize(raw) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		q.Terms = append(q.Terms, term)
+		if len(q.Terms) == maxTerms {
+			break
+		}
+	}
+	if len(q.Terms) == 0 {
+		return Query{}, ErrEmptyQuery
+	}
+	return q, nil
+}
+
+// text is the $text $search string, terms are OR'ed by mongo.
+func (q Query) text() string {
+	return strings.Join(q.Terms, " ")
+}
+
+func (q Query) ngrams() []string {
+	return models.SearchNgrams(q.Terms...)
+}
@@ -0,0 +1,187 @@
+package search
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"product-service/internal/database"
+	"product-service/internal/models"
+)
+
+const (
+	// suggestions kept per prefix, the most a request can ask for
+	MaxSuggestions = 10
+	// searches weighed in, and how far back
+	popularQueries     = 2000
+	popularQueryWindow = 30 * 24 * time.Hour
+	// events come in bursts (imports), one rebuild covers them all
+	suggestDebounce = 2 * time.Second
+	// categories are fewer and broader, they win ties against products
+	categoryWeight = 2
+)
+
+// Suggester serves completions from a trie of product names and categories,
+// rebuilt in the background. Readers never wait on a rebuild, they keep using
+// the previous trie until the new one is swapped in.
+type Suggester struct {
+	products   database.ProductRepository
+	categories database.CategoryRepository
+	searchLogs database.SearchLogRepository
+
+	trie    atomic.Pointer[Trie]
+	refresh chan struct{}
+}
+
+func NewSuggester(products database.ProductRepository, categories database.CategoryRepository, searchLogs database.SearchLogRepository) *Suggester {
+	s := &Suggester{
+		products:   products,
+		categories: categories,
+		searchLogs: searchLogs,
+		refresh:    make(chan struct{}, 1),
+	}
+	s.trie.Store(BuildTrie(nil, MaxSuggestions))
+	return s
+}
+
+func (s *Suggester) Suggest(prefix string, limit int) []Suggestion {
+	return s.trie.Load().Complete(prefix, min(limit, MaxSuggestions))
+}
+
+// Refresh asks for a rebuild, it never blocks.
+func (s *Suggester) Refresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Run builds the trie right away, then again on Refresh and every interval so
+// changes nobody announced (other replicas' creates, search popularity) show
+// up too.
+func (s *Suggester) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rebuild(ctx); err != nil {
+			log.Printf("[Suggester] rebuild failed, keeping the old trie: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(suggestDebounce):
+			}
+			// what came in while waiting is part of this rebuild
+			select {
+			case <-s.refresh:
+			default:
+			}
+		}
+	}
+}
+
+func (s *Suggester) rebuild(ctx context.Context) error {
+	start := time.Now()
+	// one suggestion per distinct text and kind, same named products share it
+	byKey := map[string]*Suggestion{}
+	var suggestions []*Suggestion
+	add := func(text, kind, productID string, weight float64) {
+		key := kind + ":" + normalize(text)
+		if strings.HasSuffix(key, ":") {
+			return
+		}
+		if have, ok := byKey[key]; ok {
+			have.Weight = max(have.Weight, weight)
+			return
+		}
+		sug := &Suggestion{Text: text, Kind: kind, ProductID: productID, Weight: weight}
+		byKey[key] = sug
+		suggestions = append(suggestions, sug)
+	}
+
+	err := s.products.EachProduct(ctx, func(p *models.Product) error {
+		add(p.Name, SuggestProduct, p.ID.Hex(), 1)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	categories, err := s.categories.ListCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		add(c.Name, SuggestCategory, "", categoryWeight)
+	}
+
+	queries, err := s.searchLogs.PopularQueries(ctx, time.Now().Add(-popularQueryWindow), popularQueries)
+	if err != nil {
+		return err
+	}
+	weighByQueries(suggestions, queries)
+
+	trie := BuildTrie(suggestions, MaxSuggestions)
+	s.trie.Store(trie)
+	log.Printf("[Suggester] trie rebuilt with %d suggestions in %s", trie.Len(), time.Since(start))
+	return nil
+}
+
+// weighByQueries adds to every suggestion the searches it answers, that is
+// the ones whose words all appear in it.
+func weighByQueries(suggestions []*Suggestion, queries []models.QueryCount) {
+	byWord := map[string][]int{}
+	for i, s := range suggestions {
+		seen := map[string]bool{}
+		for _, w := range models.Tokenize(s.Text) {
+			if !seen[w] {
+				seen[w] = true
+				byWord[w] = append(byWord[w], i)
+			}
+		}
+	}
+
+	for _, q := range queries {
+		words := models.Tokenize(q.Query)
+		if len(words) == 0 {
+			continue
+		}
+		// suggestions holding every word, starting from the first word's list
+		matches := byWord[words[0]]
+		for _, w := range words[1:] {
+			if len(matches) == 0 {
+				break
+			}
+			matches = intersect(matches, byWord[w])
+		}
+		for _, i := range matches {
+			suggestions[i].Weight += float64(q.Count)
+		}
+	}
+}
+
+// intersect of two ascending lists.
+func intersect(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
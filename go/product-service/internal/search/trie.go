@@ -0,0 +1,126 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"product-service/internal/models"
+)
+
+const (
+	SuggestProduct  = "product"
+	SuggestCategory = "category"
+)
+
+// Suggestion is a completion of what the user typed so far.
+type Suggestion struct {
+	Text      string  `json:"text"`
+	Kind      string  `json:"kind"`
+	ProductID string  `json:"product_id,omitempty"`
+	Weight    float64 `json:"-"`
+}
+
+// Trie maps normalized prefixes to their best suggestions. Every node keeps
+// its own top list, so a lookup is a walk down the prefix and nothing more.
+// It is read only once built.
+type Trie struct {
+	root *trieNode
+	size int
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	top      []*Suggestion
+}
+
+// normalize is how both the indexed texts and the typed prefixes are compared.
+func normalize(s string) string {
+	return strings.Join(models.Tokenize(s), " ")
+}
+
+// BuildTrie indexes every suggestion under its full text and under each of its
+// later words, "iphone 15 case" is found by "case" too. Each node keeps the
+// topK heaviest.
+func BuildTrie(suggestions []*Suggestion, topK int) *Trie {
+	t := &Trie{root: &trieNode{}, size: len(suggestions)}
+	for _, s := range suggestions {
+		key := normalize(s.Text)
+		for i := 0; i < len(key); i++ {
+			if i == 0 || key[i-1] == ' ' {
+				t.insert(key[i:], s, topK)
+			}
+		}
+	}
+	return t
+}
+
+func (t *Trie) insert(key string, s *Suggestion, topK int) {
+	node := t.root
+	for _, r := range key {
+		child, ok := node.children[r]
+		if !ok {
+			if node.children == nil {
+				node.children = map[rune]*trieNode{}
+			}
+			child = &trieNode{}
+			node.children[r] = child
+		}
+		child.offer(s, topK)
+		node = child
+	}
+}
+
+// offer keeps s if it ranks within the node's top k, once per node even when
+// two of its words share the prefix.
+func (n *trieNode) offer(s *Suggestion, topK int) {
+	for _, have := range n.top {
+		if have == s {
+			return
+		}
+	}
+	i := sort.Search(len(n.top), func(i int) bool { return ranksBefore(s, n.top[i]) })
+	if i >= topK {
+		return
+	}
+	n.top = append(n.top, nil)
+	copy(n.top[i+1:], n.top[i:])
+	n.top[i] = s
+	if len(n.top) > topK {
+		n.top = n.top[:topK]
+	}
+}
+
+func ranksBefore(a, b *Suggestion) bool {
+	if a.Weight != b.Weight {
+		return a.Weight > b.Weight
+	}
+	return a.Text < b.Text
+}
+
+// Complete returns up to limit suggestions for prefix, best first.
+func (t *Trie) Complete(prefix string, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	key := normalize(prefix)
+	if key == "" {
+		return suggestions
+	}
+	// "iphone " is still a prefix of "iphone 15"
+	if strings.HasSuffix(prefix, " ") {
+		key += " "
+	}
+	node := t.root
+	for _, r := range key {
+		if node = node.children[r]; node == nil {
+			return suggestions
+		}
+	}
+	for _, s := range node.top[:min(limit, len(node.top))] {
+		suggestions = append(suggestions, *s)
+	}
+	return suggestions
+}
+
+// Len is the number of distinct suggestions indexed.
+func (t *Trie) Len() int {
+	return t.size
+}
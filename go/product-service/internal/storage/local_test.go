@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorePutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir, "http://localhost:8082/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	url, err := PutContent(ctx, store, testPNG)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	key := url[len("http://localhost:8082/uploads/"):]
+	path := filepath.Join(dir, filepath.FromSlash(key))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading stored file: %v", err)
+	}
+	if string(data) != string(testPNG) {
+		t.Errorf("stored content differs")
+	}
+
+	// same key is the same content, a second put leaves the file alone
+	if err := store.Put(ctx, key, "image/png", []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(testPNG) {
+		t.Errorf("second put overwrote the file")
+	}
+	// no temp files left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("%d files in the key dir, want 1", len(entries))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still there after delete")
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "uploads"), "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../outside.png", "products/../../outside.png", ".."} {
+		if err := store.Put(context.Background(), key, "image/png", testPNG); err == nil {
+			t.Errorf("put %q accepted", key)
+		}
+		if err := store.Delete(context.Background(), key); err == nil {
+			t.Errorf("delete %q accepted", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.png")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the upload dir")
+	}
+}
+
+func TestLocalStoreHandler(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), "products/a.png", "image/png", testPNG); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.URL("products/a.png"); got != "/uploads/products/a.png" {
+		t.Errorf("url = %q", got)
+	}
+
+	srv := httptest.NewServer(http.StripPrefix("/uploads/", store.Handler()))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/uploads/products/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != string(testPNG) {
+		t.Errorf("served %s %q", resp.Status, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("content type = %q", ct)
+	}
+}
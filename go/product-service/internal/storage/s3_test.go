@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testBucket    = "products-test"
+)
+
+// png magic is all http.DetectContentType needs
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type stubObject struct {
+	data         []byte
+	contentType  string
+	cacheControl string
+}
+
+// s3Stub is a minimal S3 that verifies SigV4 the way minio does, from the
+// received request and its own copy of the secret.
+type s3Stub struct {
+	t      *testing.T
+	secret string
+	// forced status for every request, 0 serves normally
+	fail int
+
+	mu       sync.Mutex
+	objects  map[string]stubObject
+	requests []string
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *httptest.Server) {
+	stub := &s3Stub{t: t, secret: testSecretKey, objects: map[string]stubObject{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return stub, srv
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if s.fail != 0 {
+		http.Error(w, "<Error><Code>InternalError</Code></Error>", s.fail)
+		return
+	}
+	if err := s.verify(r, body); err != nil {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+err.Error()+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = stubObject{
+			data:         body,
+			contentType:  r.Header.Get("Content-Type"),
+			cacheControl: r.Header.Get("Cache-Control"),
+		}
+	case http.MethodDelete:
+		// s3 answers 204 whether or not the object existed
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *s3Stub) verify(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	const prefix = "AWS4-HMAC-SHA256 "
+	if !strings.HasPrefix(auth, prefix) {
+		return fmt.Errorf("bad authorization %q", auth)
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, prefix), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[3] != "s3" || credential[4] != "aws4_request" {
+		return fmt.Errorf("bad credential %q", fields["Credential"])
+	}
+	date, region := credential[1], credential[2]
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date) {
+		return fmt.Errorf("x-amz-date %q is not in the credential scope %s", amzDate, date)
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return fmt.Errorf("x-amz-content-sha256 does not match the body")
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return fmt.Errorf("signed headers not sorted: %v", signed)
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); fields["Signature"] != want {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func newTestS3Store(t *testing.T, endpoint, secret string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Config{
+		Endpoint:  endpoint,
+		Region:    "eu-central-1",
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3PutContentAndDelete(t *testing.T) {
+	stub, srv := newS3Stub(t)
+	store := newTestS3Store(t, srv.URL, testSecretKey)
+	ctx := context.Background()
+
+	url, err := PutContent(ctx, store, testPNG)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	sum := sha256.Sum256(testPNG)
+	key := "products/" + hex.EncodeToString(sum[:]) + ".png"
+	if want := srv.URL + "/" + testBucket + "/" + key; url != want {
+		t.Errorf("url = %q, want %q", url, want)
+	}
+
+	path := "/" + testBucket + "/" + key
+	obj, ok := stub.objects[path]
+	if !ok {
+		t.Fatalf("object not stored at %s, requests: %v", path, stub.requests)
+	}
+	if string(obj.data) != string(testPNG) {
+		t.Errorf("stored body differs")
+	}
+	if obj.contentType != "image/png" {
+		t.Errorf("content type = %q", obj.contentType)
+	}
+	if !strings.Contains(obj.cacheControl, "immutable") {
+		t.Errorf("cache control = %q, content addressed objects are immutable", obj.cacheControl)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := stub.objects[path]; ok {
+		t.Errorf("object still there after delete")
+	}
+	want := []string{"PUT " + path, "DELETE " + path}
+	if strings.Join(stub.requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", stub.requests, want)
+	}
+}
+
+func TestS3SignedHeaders(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer srv.Close()
+	store := newTestS3Store(t, srv.URL, testSecretKey)
+
+	if err := store.Delete(context.Background(), "products/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	auth := got.Header.Get("Authorization")
+	for _, want := range []string{
+		"AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/",
+		"/eu-central-1/s3/aws4_request",
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date,",
+		"Signature=",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Errorf("authorization %q lacks %q", auth, want)
+		}
+	}
+	// sha256 of an empty body
+	if h := got.Header.Get("X-Amz-Content-Sha256"); h != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("x-amz-content-sha256 = %q", h)
+	}
+	if len(got.Header.Get("X-Amz-Date")) != len("20060102T150405Z") {
+		t.Errorf("x-amz-date = %q", got.Header.Get("X-Amz-Date"))
+	}
+}
+
+func TestS3WrongSecretIsRejected(t *testing.T) {
+	_, srv := newS3Stub(t)
+	store := newTestS3Store(t, srv.URL, "not-the-secret")
+
+	err := store.Put(context.Background(), "products/a.png", "image/png", testPNG)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("err = %v, want the 403 with the s3 error body", err)
+	}
+}
+
+func TestS3ErrorStatus(t *testing.T) {
+	stub, srv := newS3Stub(t)
+	stub.fail = http.StatusServiceUnavailable
+	store := newTestS3Store(t, srv.URL, testSecretKey)
+
+	err := store.Delete(context.Background(), "products/a.png")
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "DELETE /"+testBucket+"/products/a.png") {
+		t.Fatalf("err = %v, want the 503 naming the request", err)
+	}
+}
+
+func TestNewS3StoreConfig(t *testing.T) {
+	if _, err := NewS3Store(S3Config{Endpoint: "http://localhost:9000", Bucket: "b"}); err == nil {
+		t.Error("missing keys accepted")
+	}
+	if _, err := NewS3Store(S3Config{Endpoint: "not a url", Bucket: "b", AccessKey: "a", SecretKey: "s"}); err == nil {
+		t.Error("endpoint without host accepted")
+	}
+
+	store, err := NewS3Store(S3Config{Endpoint: "http://localhost:9000/", Bucket: "b", AccessKey: "a", SecretKey: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.cfg.Region != "us-east-1" {
+		t.Errorf("default region = %q", store.cfg.Region)
+	}
+	if got := store.URL("products/x.png"); got != "http://localhost:9000/b/products/x.png" {
+		t.Errorf("url = %q", got)
+	}
+
+	store, err = NewS3Store(S3Config{Endpoint: "http://localhost:9000", Bucket: "b", AccessKey: "a", SecretKey: "s", PublicURL: "https://cdn.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.URL("products/x.png"); got != "https://cdn.example.com/products/x.png" {
+		t.Errorf("cdn url = %q", got)
+	}
+}
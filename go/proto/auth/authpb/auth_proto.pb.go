@@ -27,6 +27,7 @@ type AuthRequest struct {
 	Password       string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
 	HashedPassword string                 `protobuf:"bytes,3,opt,name=hashed_password,json=hashedPassword,proto3" json:"hashed_password,omitempty"`
 	UserId         string                 `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role           string                 `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -89,6 +90,13 @@ func (x *AuthRequest) GetUserId() string {
 	return ""
 }
 
+func (x *AuthRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
 type AuthResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
@@ -197,6 +205,7 @@ type ValidateTokenResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
 	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role          string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -245,6 +254,104 @@ func (x *ValidateTokenResponse) GetUserId() string {
 	return ""
 }
 
+func (x *ValidateTokenResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+// disabled = true rejects every token of the user until called again with false.
+// disabled = false lifts that, tokens issued before the call stay invalid so the
+// user has to log in again (used after role changes too).
+type RevokeUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Disabled      bool                   `protobuf:"varint,2,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeUserRequest) Reset() {
+	*x = RevokeUserRequest{}
+	mi := &file_auth_proto_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeUserRequest) ProtoMessage() {}
+
+func (x *RevokeUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeUserRequest.ProtoReflect.Descriptor instead.
+func (*RevokeUserRequest) Descriptor() ([]byte, []int) {
+	return file_auth_proto_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RevokeUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RevokeUserRequest) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+type RevokeUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeUserResponse) Reset() {
+	*x = RevokeUserResponse{}
+	mi := &file_auth_proto_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeUserResponse) ProtoMessage() {}
+
+func (x *RevokeUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeUserResponse.ProtoReflect.Descriptor instead.
+func (*RevokeUserResponse) Descriptor() ([]byte, []int) {
+	return file_auth_proto_proto_rawDescGZIP(), []int{5}
+}
+
 type BcryptPasswordRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Password      string                 `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
@@ -254,7 +361,7 @@ type BcryptPasswordRequest struct {
 
 func (x *BcryptPasswordRequest) Reset() {
 	*x = BcryptPasswordRequest{}
-	mi := &file_auth_proto_proto_msgTypes[4]
+	mi := &file_auth_proto_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -266,7 +373,7 @@ func (x *BcryptPasswordRequest) String() string {
 func (*BcryptPasswordRequest) ProtoMessage() {}
 
 func (x *BcryptPasswordRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_auth_proto_proto_msgTypes[4]
+	mi := &file_auth_proto_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -279,7 +386,7 @@ func (x *BcryptPasswordRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BcryptPasswordRequest.ProtoReflect.Descriptor instead.
 func (*BcryptPasswordRequest) Descriptor() ([]byte, []int) {
-	return file_auth_proto_proto_rawDescGZIP(), []int{4}
+	return file_auth_proto_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *BcryptPasswordRequest) GetPassword() string {
@@ -298,7 +405,7 @@ type BcryptPasswordResponse struct {
 
 func (x *BcryptPasswordResponse) Reset() {
 	*x = BcryptPasswordResponse{}
-	mi := &file_auth_proto_proto_msgTypes[5]
+	mi := &file_auth_proto_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -310,7 +417,7 @@ func (x *BcryptPasswordResponse) String() string {
 func (*BcryptPasswordResponse) ProtoMessage() {}
 
 func (x *BcryptPasswordResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_auth_proto_proto_msgTypes[5]
+	mi := &file_auth_proto_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +430,7 @@ func (x *BcryptPasswordResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BcryptPasswordResponse.ProtoReflect.Descriptor instead.
 func (*BcryptPasswordResponse) Descriptor() ([]byte, []int) {
-	return file_auth_proto_proto_rawDescGZIP(), []int{5}
+	return file_auth_proto_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *BcryptPasswordResponse) GetHashedPassword() string {
@@ -337,29 +444,37 @@ var File_auth_proto_proto protoreflect.FileDescriptor
 
 const file_auth_proto_proto_rawDesc = "" +
 	"\n" +
-	"\x10auth_proto.proto\x12\x04auth\"\x81\x01\n" +
+	"\x10auth_proto.proto\x12\x04auth\"\x95\x01\n" +
 	"\vAuthRequest\x12\x14\n" +
 	"\x05email\x18\x01 \x01(\tR\x05email\x12\x1a\n" +
 	"\bpassword\x18\x02 \x01(\tR\bpassword\x12'\n" +
 	"\x0fhashed_password\x18\x03 \x01(\tR\x0ehashedPassword\x12\x17\n" +
-	"\auser_id\x18\x04 \x01(\tR\x06userId\"S\n" +
+	"\auser_id\x18\x04 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04role\x18\x05 \x01(\tR\x04role\"S\n" +
 	"\fAuthResponse\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
 	"\x05valid\x18\x03 \x01(\bR\x05valid\",\n" +
 	"\x14ValidateTokenRequest\x12\x14\n" +
-	"\x05token\x18\x01 \x01(\tR\x05token\"F\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"Z\n" +
 	"\x15ValidateTokenResponse\x12\x14\n" +
 	"\x05valid\x18\x01 \x01(\bR\x05valid\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\"3\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04role\x18\x03 \x01(\tR\x04role\"H\n" +
+	"\x11RevokeUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\bdisabled\x18\x02 \x01(\bR\bdisabled\"\x14\n" +
+	"\x12RevokeUserResponse\"3\n" +
 	"\x15BcryptPasswordRequest\x12\x1a\n" +
 	"\bpassword\x18\x01 \x01(\tR\bpassword\"@\n" +
 	"\x16BcryptPasswordResponse\x12&\n" +
-	"\x0ehashedPassword\x18\x01 \x01(\tR\x0ehashedPassword2\xdd\x01\n" +
+	"\x0ehashedPassword\x18\x01 \x01(\tR\x0ehashedPassword2\x9e\x02\n" +
 	"\vAuthService\x12M\n" +
 	"\x10GeneratePassword\x12\x1b.auth.BcryptPasswordRequest\x1a\x1c.auth.BcryptPasswordResponse\x125\n" +
 	"\fAuthenticate\x12\x11.auth.AuthRequest\x1a\x12.auth.AuthResponse\x12H\n" +
-	"\rValidateToken\x12\x1a.auth.ValidateTokenRequest\x1a\x1b.auth.ValidateTokenResponseB\n" +
+	"\rValidateToken\x12\x1a.auth.ValidateTokenRequest\x1a\x1b.auth.ValidateTokenResponse\x12?\n" +
+	"\n" +
+	"RevokeUser\x12\x17.auth.RevokeUserRequest\x1a\x18.auth.RevokeUserResponseB\n" +
 	"Z\b./authpbb\x06proto3"
 
 var (
@@ -374,24 +489,28 @@ func file_auth_proto_proto_rawDescGZIP() []byte {
 	return file_auth_proto_proto_rawDescData
 }
 
-var file_auth_proto_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_auth_proto_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_auth_proto_proto_goTypes = []any{
 	(*AuthRequest)(nil),            // 0: auth.AuthRequest
 	(*AuthResponse)(nil),           // 1: auth.AuthResponse
 	(*ValidateTokenRequest)(nil),   // 2: auth.ValidateTokenRequest
 	(*ValidateTokenResponse)(nil),  // 3: auth.ValidateTokenResponse
-	(*BcryptPasswordRequest)(nil),  // 4: auth.BcryptPasswordRequest
-	(*BcryptPasswordResponse)(nil), // 5: auth.BcryptPasswordResponse
+	(*RevokeUserRequest)(nil),      // 4: auth.RevokeUserRequest
+	(*RevokeUserResponse)(nil),     // 5: auth.RevokeUserResponse
+	(*BcryptPasswordRequest)(nil),  // 6: auth.BcryptPasswordRequest
+	(*BcryptPasswordResponse)(nil), // 7: auth.BcryptPasswordResponse
 }
 var file_auth_proto_proto_depIdxs = []int32{
-	4, // 0: auth.AuthService.GeneratePassword:input_type -> auth.BcryptPasswordRequest
+	6, // 0: auth.AuthService.GeneratePassword:input_type -> auth.BcryptPasswordRequest
 	0, // 1: auth.AuthService.Authenticate:input_type -> auth.AuthRequest
 	2, // 2: auth.AuthService.ValidateToken:input_type -> auth.ValidateTokenRequest
-	5, // 3: auth.AuthService.GeneratePassword:output_type -> auth.BcryptPasswordResponse
-	1, // 4: auth.AuthService.Authenticate:output_type -> auth.AuthResponse
-	3, // 5: auth.AuthService.ValidateToken:output_type -> auth.ValidateTokenResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
+	4, // 3: auth.AuthService.RevokeUser:input_type -> auth.RevokeUserRequest
+	7, // 4: auth.AuthService.GeneratePassword:output_type -> auth.BcryptPasswordResponse
+	1, // 5: auth.AuthService.Authenticate:output_type -> auth.AuthResponse
+	3, // 6: auth.AuthService.ValidateToken:output_type -> auth.ValidateTokenResponse
+	5, // 7: auth.AuthService.RevokeUser:output_type -> auth.RevokeUserResponse
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -408,7 +527,7 @@ func file_auth_proto_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_auth_proto_proto_rawDesc), len(file_auth_proto_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
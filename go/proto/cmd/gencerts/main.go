@@ -0,0 +1,124 @@
+// gencerts writes a throwaway dev CA plus one cert per service for mTLS.
+//
+//	go run ./cmd/gencerts -out ../certs
+//
+// Each service cert has CN = service name (used by the allow-lists) and
+// SANs for the service name and localhost so it works in docker and locally.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var defaultServices = []string{
+	"api-gateway",
+	"auth-service",
+	"user-service",
+	"product-service",
+	"cart-service",
+	"payment-service",
+	"notification-service",
+	"order-service",
+	"dev-client",
+}
+
+func main() {
+	out := flag.String("out", "certs", "output dir")
+	services := flag.String("services", strings.Join(defaultServices, ","), "comma separated service names")
+	validFor := flag.Duration("valid", 365*24*time.Hour, "cert validity")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("couldnt create out dir: %v", err)
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatalf("ca key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{CommonName: "microservices dev ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(*validFor),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		log.Fatalf("ca cert: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		log.Fatalf("parse ca: %v", err)
+	}
+	write(filepath.Join(*out, "ca.pem"), "CERTIFICATE", caDER)
+
+	for _, name := range strings.Split(*services, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			log.Fatalf("%s key: %v", name, err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: serial(),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name, "localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(*validFor),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			// every service is both a grpc server and a client of someone else
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			log.Fatalf("%s cert: %v", name, err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			log.Fatalf("%s marshal key: %v", name, err)
+		}
+		write(filepath.Join(*out, name+".pem"), "CERTIFICATE", der)
+		write(filepath.Join(*out, name+"-key.pem"), "PRIVATE KEY", keyDER)
+	}
+
+	fmt.Printf("certs written to %s\n", *out)
+}
+
+func serial() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		log.Fatalf("serial: %v", err)
+	}
+	return n
+}
+
+func write(path, blockType string, der []byte) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		log.Fatalf("write %s: %v", path, err)
+	}
+}
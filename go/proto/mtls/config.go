@@ -0,0 +1,78 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Config points to the PEM files every service needs for mutual TLS.
+// CA is the dev/prod CA that signed all service certs.
+type Config struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+func FromEnv() Config {
+	return Config{
+		CAFile:   os.Getenv("TLS_CA_FILE"),
+		CertFile: os.Getenv("TLS_CERT_FILE"),
+		KeyFile:  os.Getenv("TLS_KEY_FILE"),
+	}
+}
+
+func (c Config) load() (tls.Certificate, *x509.CertPool, error) {
+	if c.CAFile == "" || c.CertFile == "" || c.KeyFile == "" {
+		return tls.Certificate{}, nil, errors.New("missing TLS_CA_FILE, TLS_CERT_FILE or TLS_KEY_FILE")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("load key pair: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("read ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return tls.Certificate{}, nil, errors.New("no certs found in ca file")
+	}
+
+	return cert, pool, nil
+}
+
+// ServerCredentials requires and verifies a client cert signed by the CA.
+func ServerCredentials(c Config) (credentials.TransportCredentials, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+// ClientCredentials presents our cert and checks the server cert is issued
+// for serverName (the target service name, e.g. "auth-service").
+func ClientCredentials(c Config, serverName string) (credentials.TransportCredentials, error) {
+	cert, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"grpc_module/auth/authpb"
+	"grpc_module/mtls"
 	"grpc_module/user/userpb"
 	"log"
 	"net"
@@ -17,13 +18,13 @@ import (
 	"user-service/internal/database"
 	handler "user-service/internal/handlers"
 	"user-service/internal/kafka"
+	"user-service/internal/models"
 	"user-service/logger"
 
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -40,11 +41,31 @@ func main() {
 	authAddr := os.Getenv("AUTH_SERVICE_ADDR")
 	logDev := os.Getenv("LOG_DEV")
 	topic := os.Getenv("KAFKA_TOPIC")
+	if topic == "" {
+		// cart-service and payment-service read user events from here
+		topic = "user-events"
+	}
 	brokersEnv := os.Getenv("KAFKA_BROKERS")
 	if brokersEnv == "" {
 		brokersEnv = "localhost:9092"
 	}
 	brokers := strings.Split(brokersEnv, ",")
+	// promoted to admin on startup so there's someone to manage the rest
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+	verificationTopic := os.Getenv("VERIFICATION_TOPIC")
+	if verificationTopic == "" {
+		verificationTopic = "user-verification"
+	}
+	privacyTopic := os.Getenv("PRIVACY_TOPIC")
+	if privacyTopic == "" {
+		privacyTopic = "privacy-events"
+	}
+	// services that must answer export/erasure jobs, add order-service here once it exists
+	privacyServicesEnv := os.Getenv("PRIVACY_SERVICES")
+	if privacyServicesEnv == "" {
+		privacyServicesEnv = "cart-service,payment-service"
+	}
+	privacyServices := strings.Split(privacyServicesEnv, ",")
 
 	// init logger
 	logMode, err := strconv.ParseBool(logDev)
@@ -65,7 +86,21 @@ func main() {
 		log.Fatalf("[Error]: Failed to connect mongodb: %v", err)
 	}
 	repo := database.NewMongoRepo(client, dbName)
-	authConn, err := grpc.NewClient("localhost:"+authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	addressRepo := database.NewMongoAddressRepo(client, dbName)
+	privacyRepo := database.NewMongoPrivacyRepo(client, dbName)
+	auditRepo := database.NewMongoAuditRepo(client, dbName)
+	preferencesRepo := database.NewMongoPreferencesRepo(client, dbName)
+	if adminEmail != "" {
+		bootstrapAdmin(repo, adminEmail)
+	}
+
+	// mtls for every grpc hop
+	tlsCfg := mtls.FromEnv()
+	authCreds, err := mtls.ClientCredentials(tlsCfg, "auth-service")
+	if err != nil {
+		log.Fatalf("[Error]: Failed to load mtls client config: %v", err)
+	}
+	authConn, err := grpc.NewClient("localhost:"+authAddr, grpc.WithTransportCredentials(authCreds))
 	if err != nil {
 		log.Fatalf("[Error]: Failed to connect to AuthService: %v", err)
 	}
@@ -73,8 +108,26 @@ func main() {
 
 	// init services
 	authClient := authpb.NewAuthServiceClient(authConn)
-	userProducer := kafka.NewUserProducer(brokers, topic)
-	userHandler := handler.NewUserHandler(repo, logger, authClient, userProducer)
+	userProducer := kafka.NewUserProducer(brokers, topic, verificationTopic)
+	userHandler := handler.NewUserHandler(repo, addressRepo, privacyRepo, auditRepo, preferencesRepo, logger, authClient, userProducer, privacyServices)
+	privacyConsumer := kafka.NewPrivacyConsumer(brokers, privacyTopic, "user-service-privacy-group", privacyRepo)
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	go func() {
+		if err := privacyConsumer.Consume(consumerCtx); err != nil {
+			log.Printf("[Error]: PrivacyConsumer: %v", err)
+		}
+	}()
+
+	go func() {
+		n, err := userHandler.BackfillUnsubscribeTokens(consumerCtx)
+		if err != nil {
+			log.Printf("[Error]: backfilling unsubscribe tokens: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("backfilled %d unsubscribe tokens", n)
+		}
+	}()
 
 	// Set up HTTP handlers
 	http.Handle("/", userHandler.Routes())
@@ -92,7 +145,20 @@ func main() {
 	}
 
 	// start grpc server
-	server := grpc.NewServer()
+	allow := mtls.AllowList{
+		"/user.UserService/VerifyCredentials": {"api-gateway"},
+		"/user.UserService/GetAddress":        {"order-service"},
+		"/user.UserService/GetUser":           {"notification-service", "order-service"},
+		"/user.UserService/BatchGetUsers":     {"notification-service", "order-service"},
+		"/user.UserService/GetUserByEmail":    {"notification-service"},
+		"/user.UserService/ListUsers":         {"auth-service"},
+		"/user.UserService/GetPreferences":    {"notification-service"},
+	}.WithReflection(mtls.DevClient)
+	serverOpts, err := mtls.ServerOptions(tlsCfg, allow)
+	if err != nil {
+		log.Fatalf("[Error]: Failed to load mtls server config: %v", err)
+	}
+	server := grpc.NewServer(serverOpts...)
 	userpb.RegisterUserServiceServer(server, userHandler)
 	reflection.Register(server)
 
@@ -102,6 +168,11 @@ func main() {
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 		log.Println("[Shutting down]: user service gracefully")
+		log.Println("[Shutting down]: PrivacyConsumer")
+		stopConsumer()
+		if err := privacyConsumer.Close(); err != nil {
+			log.Printf("[Error]: closing PrivacyConsumer: %v", err)
+		}
 		log.Println("[Shutting down]: UserProducer")
 		if err := userProducer.Close(); err != nil {
 			log.Printf("[Error]: closing UserProducer: %v", err)
@@ -122,3 +193,26 @@ func main() {
 		log.Fatalf("[Error]: Failed to serve: %v", err)
 	}
 }
+
+func bootstrapAdmin(repo database.UserRepository, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("[Error]: looking up ADMIN_EMAIL: %v", err)
+		return
+	}
+	if user == nil {
+		log.Printf("[Warn]: ADMIN_EMAIL %s has no account yet, register it and restart", email)
+		return
+	}
+	if user.GetRole() == models.RoleAdmin {
+		return
+	}
+	if err := repo.SetRole(ctx, user.ID.Hex(), models.RoleAdmin); err != nil {
+		log.Printf("[Error]: promoting ADMIN_EMAIL: %v", err)
+		return
+	}
+	log.Printf("promoted %s to admin", email)
+}
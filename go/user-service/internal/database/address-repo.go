@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type AddressRepository interface {
+	ListAddresses(ctx context.Context, userID string) ([]*models.Address, error)
+	GetAddress(ctx context.Context, userID, id string) (*models.Address, error)
+	GetDefaultAddress(ctx context.Context, userID string, kind models.AddressKind) (*models.Address, error)
+	CreateAddress(ctx context.Context, address *models.Address) error
+	UpdateAddress(ctx context.Context, address *models.Address) error
+	DeleteAddress(ctx context.Context, userID, id string) error
+	DeleteAllAddresses(ctx context.Context, userID string) error
+}
+
+type mongoAddressRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoAddressRepo(client *mongo.Client, dbName string) *mongoAddressRepo {
+	col := client.Database(dbName).Collection("addresses")
+
+	idxModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+	_, _ = col.Indexes().CreateOne(context.Background(), idxModel)
+
+	return &mongoAddressRepo{col: col}
+}
+
+func (repo *mongoAddressRepo) ListAddresses(ctx context.Context, userID string) ([]*models.Address, error) {
+	addresses := []*models.Address{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	res, err := repo.col.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+
+	for res.Next(ctx) {
+		var address models.Address
+		if err := res.Decode(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, &address)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (repo *mongoAddressRepo) GetAddress(ctx context.Context, userID, id string) (*models.Address, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid address id format")
+	}
+	return repo.findOne(ctx, bson.M{"_id": objectId, "user_id": userID})
+}
+
+func (repo *mongoAddressRepo) GetDefaultAddress(ctx context.Context, userID string, kind models.AddressKind) (*models.Address, error) {
+	return repo.findOne(ctx, bson.M{"user_id": userID, defaultField(kind): true})
+}
+
+func (repo *mongoAddressRepo) findOne(ctx context.Context, filter bson.M) (*models.Address, error) {
+	var address models.Address
+	err := repo.col.FindOne(ctx, filter).Decode(&address)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (repo *mongoAddressRepo) CreateAddress(ctx context.Context, address *models.Address) error {
+	address.ID = primitive.NewObjectID()
+	now := time.Now()
+	address.CreatedAt = now
+	address.UpdatedAt = now
+
+	// first address becomes the default for both
+	count, err := repo.col.CountDocuments(ctx, bson.M{"user_id": address.UserID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		address.DefaultShipping = true
+		address.DefaultBilling = true
+	}
+
+	if _, err := repo.col.InsertOne(ctx, address); err != nil {
+		return err
+	}
+	return repo.clearOtherDefaults(ctx, address)
+}
+
+func (repo *mongoAddressRepo) UpdateAddress(ctx context.Context, address *models.Address) error {
+	address.UpdatedAt = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"full_name":        address.FullName,
+			"line1":            address.Line1,
+			"line2":            address.Line2,
+			"city":             address.City,
+			"region":           address.Region,
+			"postal_code":      address.PostalCode,
+			"country":          address.Country,
+			"phone":            address.Phone,
+			"default_shipping": address.DefaultShipping,
+			"default_billing":  address.DefaultBilling,
+			"updated_at":       address.UpdatedAt,
+		},
+	}
+	res, err := repo.col.UpdateOne(ctx, bson.M{"_id": address.ID, "user_id": address.UserID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("address not found")
+	}
+	return repo.clearOtherDefaults(ctx, address)
+}
+
+func (repo *mongoAddressRepo) DeleteAddress(ctx context.Context, userID, id string) error {
+	address, err := repo.GetAddress(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if address == nil {
+		return errors.New("address not found")
+	}
+
+	if _, err := repo.col.DeleteOne(ctx, bson.M{"_id": address.ID, "user_id": userID}); err != nil {
+		return err
+	}
+
+	// hand the default over to the newest remaining address
+	for kind, wasDefault := range map[models.AddressKind]bool{
+		models.AddressShipping: address.DefaultShipping,
+		models.AddressBilling:  address.DefaultBilling,
+	} {
+		if !wasDefault {
+			continue
+		}
+		opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		err := repo.col.FindOneAndUpdate(ctx,
+			bson.M{"user_id": userID},
+			bson.M{"$set": bson.M{defaultField(kind): true}},
+			opts,
+		).Err()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *mongoAddressRepo) DeleteAllAddresses(ctx context.Context, userID string) error {
+	_, err := repo.col.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// only one default shipping/billing address per user
+func (repo *mongoAddressRepo) clearOtherDefaults(ctx context.Context, address *models.Address) error {
+	unset := bson.M{}
+	if address.DefaultShipping {
+		unset[defaultField(models.AddressShipping)] = false
+	}
+	if address.DefaultBilling {
+		unset[defaultField(models.AddressBilling)] = false
+	}
+	if len(unset) == 0 {
+		return nil
+	}
+	_, err := repo.col.UpdateMany(ctx,
+		bson.M{"user_id": address.UserID, "_id": bson.M{"$ne": address.ID}},
+		bson.M{"$set": unset},
+	)
+	return err
+}
+
+func defaultField(kind models.AddressKind) string {
+	if kind == models.AddressBilling {
+		return "default_billing"
+	}
+	return "default_shipping"
+}
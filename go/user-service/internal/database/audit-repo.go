@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"time"
+	"user-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// ListEntries filters on actor and/or target when set, newest first.
+	ListEntries(ctx context.Context, actorID, targetID string, limit, skip int64) ([]*models.AuditEntry, error)
+}
+
+type mongoAuditRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoAuditRepo(client *mongo.Client, dbName string) *mongoAuditRepo {
+	col := client.Database(dbName).Collection("admin_audit")
+
+	idxModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "time", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "time", Value: -1}}},
+	}
+	_, _ = col.Indexes().CreateMany(context.Background(), idxModels)
+
+	return &mongoAuditRepo{col: col}
+}
+
+func (repo *mongoAuditRepo) Record(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	entry.Time = time.Now()
+	_, err := repo.col.InsertOne(ctx, entry)
+	return err
+}
+
+func (repo *mongoAuditRepo) ListEntries(ctx context.Context, actorID, targetID string, limit, skip int64) ([]*models.AuditEntry, error) {
+	entries := []*models.AuditEntry{}
+
+	query := bson.M{}
+	if actorID != "" {
+		query["actor_id"] = actorID
+	}
+	if targetID != "" {
+		query["target_id"] = targetID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	if skip > 0 {
+		opts.SetSkip(skip)
+	}
+
+	res, err := repo.col.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+
+	for res.Next(ctx) {
+		var entry models.AuditEntry
+		if err := res.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
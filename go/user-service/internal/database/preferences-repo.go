@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type PreferencesRepository interface {
+	// GetPreferences returns nil, nil when the user never saved any.
+	GetPreferences(ctx context.Context, userID string) (*models.Preferences, error)
+	GetPreferencesByUnsubscribeToken(ctx context.Context, token string) (*models.Preferences, error)
+	SavePreferences(ctx context.Context, prefs *models.Preferences) error
+	DeletePreferences(ctx context.Context, userID string) error
+	// EnsureUnsubscribeToken gives the user token unless they have one,
+	// creating default preferences for users who never saved any
+	EnsureUnsubscribeToken(ctx context.Context, userID, token string) error
+	// WithoutUnsubscribeToken lists up to limit users who have no token yet
+	WithoutUnsubscribeToken(ctx context.Context, limit int64) ([]string, error)
+}
+
+type mongoPreferencesRepo struct {
+	col   *mongo.Collection
+	users *mongo.Collection
+}
+
+func NewMongoPreferencesRepo(client *mongo.Client, dbName string) *mongoPreferencesRepo {
+	col := client.Database(dbName).Collection("preferences")
+
+	idxModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "unsubscribe_token", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	}
+	_, _ = col.Indexes().CreateOne(context.Background(), idxModel)
+
+	return &mongoPreferencesRepo{col: col, users: client.Database(dbName).Collection("users_db")}
+}
+
+func (repo *mongoPreferencesRepo) GetPreferences(ctx context.Context, userID string) (*models.Preferences, error) {
+	return repo.findOne(ctx, bson.M{"_id": userID})
+}
+
+func (repo *mongoPreferencesRepo) GetPreferencesByUnsubscribeToken(ctx context.Context, token string) (*models.Preferences, error) {
+	return repo.findOne(ctx, bson.M{"unsubscribe_token": token})
+}
+
+func (repo *mongoPreferencesRepo) findOne(ctx context.Context, filter bson.M) (*models.Preferences, error) {
+	var prefs models.Preferences
+	err := repo.col.FindOne(ctx, filter).Decode(&prefs)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (repo *mongoPreferencesRepo) SavePreferences(ctx context.Context, prefs *models.Preferences) error {
+	prefs.UpdatedAt = time.Now()
+	_, err := repo.col.ReplaceOne(ctx, bson.M{"_id": prefs.UserID}, prefs, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (repo *mongoPreferencesRepo) DeletePreferences(ctx context.Context, userID string) error {
+	_, err := repo.col.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
+
+func (repo *mongoPreferencesRepo) EnsureUnsubscribeToken(ctx context.Context, userID, token string) error {
+	defaults := models.DefaultPreferences(userID)
+	_, err := repo.col.UpdateOne(ctx,
+		bson.M{"_id": userID, "unsubscribe_token": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{
+			"$set": bson.M{"unsubscribe_token": token},
+			"$setOnInsert": bson.M{
+				"marketing_opt_in": defaults.MarketingOptIn,
+				"channels":         defaults.Channels,
+				"locale":           defaults.Locale,
+				"currency":         defaults.Currency,
+				"updated_at":       time.Now(),
+			},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	// the user has a token already, so the upsert collided on _id
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *mongoPreferencesRepo) WithoutUnsubscribeToken(ctx context.Context, limit int64) ([]string, error) {
+	// preferences are keyed by the hex of the user's object id
+	pipeline := mongo.Pipeline{
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": repo.col.Name(),
+			"let":  bson.M{"id": bson.M{"$toString": "$_id"}},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$id"}}}},
+				bson.M{"$project": bson.M{"unsubscribe_token": 1}},
+			},
+			"as": "prefs",
+		}}},
+		{{Key: "$match", Value: bson.M{"prefs": bson.M{"$not": bson.M{"$elemMatch": bson.M{"unsubscribe_token": bson.M{"$nin": bson.A{nil, ""}}}}}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cur, err := repo.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	ids := []string{}
+	for cur.Next(ctx) {
+		var user struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&user); err != nil {
+			return nil, err
+		}
+		ids = append(ids, user.ID.Hex())
+	}
+	return ids, cur.Err()
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type PrivacyJobRepository interface {
+	CreateJob(ctx context.Context, job *models.PrivacyJob) error
+	GetJob(ctx context.Context, userID, id string) (*models.PrivacyJob, error)
+	// CompleteService records a service's answer, part is only set for exports.
+	// Returns the job after the update, nil if it doesn't exist.
+	CompleteService(ctx context.Context, id, service, part string) (*models.PrivacyJob, error)
+	// FailJob closes a pending job that can't finish
+	FailJob(ctx context.Context, id primitive.ObjectID, reason string) error
+}
+
+// how long a finished export can be downloaded
+const exportRetention = 7 * 24 * time.Hour
+
+type mongoPrivacyRepo struct {
+	col *mongo.Collection
+}
+
+func NewMongoPrivacyRepo(client *mongo.Client, dbName string) *mongoPrivacyRepo {
+	col := client.Database(dbName).Collection("privacy_jobs")
+
+	idxModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	_, _ = col.Indexes().CreateMany(context.Background(), idxModels)
+
+	return &mongoPrivacyRepo{col: col}
+}
+
+func (repo *mongoPrivacyRepo) CreateJob(ctx context.Context, job *models.PrivacyJob) error {
+	job.ID = primitive.NewObjectID()
+	job.CreatedAt = time.Now()
+	job.Status = models.PrivacyPending
+	if job.Kind == models.PrivacyExport {
+		job.ExpiresAt = job.CreatedAt.Add(exportRetention)
+	}
+	if len(job.Pending) == 0 {
+		job.Status = models.PrivacyCompleted
+		job.CompletedAt = job.CreatedAt
+	}
+
+	_, err := repo.col.InsertOne(ctx, job)
+	return err
+}
+
+func (repo *mongoPrivacyRepo) GetJob(ctx context.Context, userID, id string) (*models.PrivacyJob, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid job id format")
+	}
+	var job models.PrivacyJob
+	err = repo.col.FindOne(ctx, bson.M{"_id": objectId, "user_id": userID}).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (repo *mongoPrivacyRepo) CompleteService(ctx context.Context, id, service, part string) (*models.PrivacyJob, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid job id format")
+	}
+
+	set := bson.M{}
+	if part != "" {
+		set["parts."+service] = part
+	}
+	update := bson.M{"$pull": bson.M{"pending": service}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var job models.PrivacyJob
+	err = repo.col.FindOneAndUpdate(ctx, bson.M{"_id": objectId}, update, opts).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(job.Pending) > 0 || job.Status == models.PrivacyCompleted {
+		return &job, nil
+	}
+
+	// last one in closes the job, the status filter keeps redelivered acks from re-stamping it
+	job.Status = models.PrivacyCompleted
+	job.CompletedAt = time.Now()
+	_, err = repo.col.UpdateOne(ctx,
+		bson.M{"_id": objectId, "status": models.PrivacyPending},
+		bson.M{"$set": bson.M{"status": job.Status, "completed_at": job.CompletedAt}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (repo *mongoPrivacyRepo) FailJob(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := repo.col.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.PrivacyPending},
+		bson.M{"$set": bson.M{"status": models.PrivacyFailed, "error": reason, "completed_at": time.Now()}},
+	)
+	return err
+}
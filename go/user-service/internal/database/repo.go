@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"regexp"
 	"time"
 	"user-service/internal/models"
 
@@ -16,10 +17,33 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserById(ctx context.Context, id string) (*models.User, error)
+	GetUsersByIds(ctx context.Context, ids []string) ([]*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id string) error
+	UpdatePassword(ctx context.Context, id string, hashedPassword string) error
+	SetPendingEmail(ctx context.Context, id string, email string, token string, expires time.Time) error
+	ConfirmEmail(ctx context.Context, token string) (*models.User, error)
+	ListUsers(ctx context.Context, filter UserFilter) ([]*models.User, int64, error)
+	SetStatus(ctx context.Context, id string, status models.UserStatus, reason string) error
+	SetRole(ctx context.Context, id string, role models.Role) error
 }
 
+// UserFilter is for the admin listing, zero values mean no filter.
+type UserFilter struct {
+	Email         string // case-insensitive substring
+	Role          models.Role
+	Status        models.UserStatus
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int64
+	Skip          int64
+}
+
+var (
+	ErrEmailTaken    = errors.New("email already in use")
+	ErrInvalidUserID = errors.New("invalid user id format")
+)
+
 type mongoRepo struct {
 	col *mongo.Collection
 }
@@ -27,11 +51,18 @@ type mongoRepo struct {
 func NewMongoRepo(client *mongo.Client, dbName string) *mongoRepo {
 	col := client.Database(dbName).Collection("users_db")
 
-	idxModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: "email", Value: 1}},
-		Options: options.Index().SetUnique(true),
+	idxModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// ConfirmEmail looks users up by the token in the link
+			Keys:    bson.D{{Key: "email_verify_token", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
 	}
-	_, _ = col.Indexes().CreateOne(context.Background(), idxModel)
+	_, _ = col.Indexes().CreateMany(context.Background(), idxModels)
 
 	return &mongoRepo{col: col}
 }
@@ -67,7 +98,7 @@ func (repo *mongoRepo) GetUserByEmail(ctx context.Context, email string) (*model
 func (repo *mongoRepo) GetUserById(ctx context.Context, id string) (*models.User, error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid user id format")
+		return nil, ErrInvalidUserID
 	}
 	var user models.User
 	err = repo.col.FindOne(ctx, bson.M{"_id": objectId}).Decode(&user)
@@ -80,6 +111,41 @@ func (repo *mongoRepo) GetUserById(ctx context.Context, id string) (*models.User
 	return &user, nil
 }
 
+// GetUsersByIds skips ids that are malformed or don't exist, callers diff the result.
+func (repo *mongoRepo) GetUsersByIds(ctx context.Context, ids []string) ([]*models.User, error) {
+	users := []*models.User{}
+
+	objectIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		objectIds = append(objectIds, objectId)
+	}
+	if len(objectIds) == 0 {
+		return users, nil
+	}
+
+	res, err := repo.col.Find(ctx, bson.M{"_id": bson.M{"$in": objectIds}})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close(ctx)
+
+	for res.Next(ctx) {
+		var user models.User
+		if err := res.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (repo *mongoRepo) UpdateUser(ctx context.Context, user *models.User) error {
 	user.UpdatedAt = time.Now()
 
@@ -106,7 +172,7 @@ func (repo *mongoRepo) UpdateUser(ctx context.Context, user *models.User) error
 func (repo *mongoRepo) DeleteUser(ctx context.Context, id string) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid user id format")
+		return ErrInvalidUserID
 	}
 
 	res, err := repo.col.DeleteOne(ctx, bson.M{"_id": objectId})
@@ -120,3 +186,183 @@ func (repo *mongoRepo) DeleteUser(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (repo *mongoRepo) UpdatePassword(ctx context.Context, id string, hashedPassword string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"password":   hashedPassword,
+			"updated_at": time.Now(),
+		},
+	}
+	res, err := repo.col.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (repo *mongoRepo) SetPendingEmail(ctx context.Context, id string, email string, token string, expires time.Time) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"pending_email":        email,
+			"email_verify_token":   token,
+			"email_verify_expires": expires,
+			"updated_at":           time.Now(),
+		},
+	}
+	res, err := repo.col.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// ConfirmEmail swaps pending_email into email for the user holding token.
+// Returns nil, nil when the token doesn't match anyone or has expired.
+func (repo *mongoRepo) ConfirmEmail(ctx context.Context, token string) (*models.User, error) {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"email": "$pending_email", "updated_at": time.Now()}}},
+		{{Key: "$unset", Value: bson.A{"pending_email", "email_verify_token", "email_verify_expires"}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user models.User
+	err := repo.col.FindOneAndUpdate(ctx, bson.M{
+		"email_verify_token":   token,
+		"email_verify_expires": bson.M{"$gt": time.Now()},
+	}, update, opts).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (repo *mongoRepo) ListUsers(ctx context.Context, filter UserFilter) ([]*models.User, int64, error) {
+	users := []*models.User{}
+
+	query := bson.M{}
+	if filter.Email != "" {
+		query["email"] = bson.M{"$regex": regexp.QuoteMeta(filter.Email), "$options": "i"}
+	}
+	// older accounts have no role/status field, they count as customer/active
+	if filter.Role == models.RoleCustomer {
+		query["role"] = bson.M{"$in": bson.A{filter.Role, nil}}
+	} else if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.Status == models.StatusActive {
+		query["status"] = bson.M{"$in": bson.A{filter.Status, nil}}
+	} else if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	created := bson.M{}
+	if !filter.CreatedAfter.IsZero() {
+		created["$gte"] = filter.CreatedAfter
+	}
+	if !filter.CreatedBefore.IsZero() {
+		created["$lt"] = filter.CreatedBefore
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+
+	total, err := repo.col.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	if filter.Skip > 0 {
+		opts.SetSkip(filter.Skip)
+	}
+	res, err := repo.col.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Close(ctx)
+
+	for res.Next(ctx) {
+		var user models.User
+		if err := res.Decode(&user); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+	if err := res.Err(); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (repo *mongoRepo) SetStatus(ctx context.Context, id string, status models.UserStatus, reason string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	set := bson.M{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if reason != "" {
+		set["disabled_reason"] = reason
+	} else {
+		update["$unset"] = bson.M{"disabled_reason": ""}
+	}
+	res, err := repo.col.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (repo *mongoRepo) SetRole(ctx context.Context, id string, role models.Role) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidUserID
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"role":       role,
+			"updated_at": time.Now(),
+		},
+	}
+	res, err := repo.col.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
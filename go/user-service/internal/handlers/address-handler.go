@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"grpc_module/user/userpb"
+	"user-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type addressRequest struct {
+	FullName        string `json:"full_name"`
+	Line1           string `json:"line1"`
+	Line2           string `json:"line2"`
+	City            string `json:"city"`
+	Region          string `json:"region"`
+	PostalCode      string `json:"postal_code"`
+	Country         string `json:"country"`
+	Phone           string `json:"phone"`
+	DefaultShipping bool   `json:"default_shipping"`
+	DefaultBilling  bool   `json:"default_billing"`
+}
+
+func (req addressRequest) apply(a *models.Address) {
+	a.FullName = req.FullName
+	a.Line1 = req.Line1
+	a.Line2 = req.Line2
+	a.City = req.City
+	a.Region = req.Region
+	a.PostalCode = req.PostalCode
+	a.Country = req.Country
+	a.Phone = req.Phone
+	a.DefaultShipping = req.DefaultShipping
+	a.DefaultBilling = req.DefaultBilling
+	a.Normalize()
+}
+
+func (h *UserHandler) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	addresses, err := h.addressRepo.ListAddresses(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err listing addresses", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(addresses)
+}
+
+func (h *UserHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req addressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err in decoding json", zap.Error(err))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	address := &models.Address{UserID: userID}
+	req.apply(address)
+	if err := address.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.addressRepo.CreateAddress(r.Context(), address); err != nil {
+		h.logger.Error("err creating address", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("address created", zap.String("user_id", userID), zap.String("address_id", address.ID.Hex()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(address)
+}
+
+func (h *UserHandler) GetAddressHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := h.addressRepo.GetAddress(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		h.logger.Warn("err fetching address", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "invalid address id", http.StatusBadRequest)
+		return
+	}
+	if address == nil {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(address)
+}
+
+func (h *UserHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := h.addressRepo.GetAddress(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		h.logger.Warn("err fetching address", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "invalid address id", http.StatusBadRequest)
+		return
+	}
+	if address == nil {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	var req addressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err in decoding json", zap.Error(err))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	// can't drop the default flag by editing, set another address as default instead
+	req.DefaultShipping = req.DefaultShipping || address.DefaultShipping
+	req.DefaultBilling = req.DefaultBilling || address.DefaultBilling
+	req.apply(address)
+	if err := address.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.addressRepo.UpdateAddress(r.Context(), address); err != nil {
+		h.logger.Error("err updating address", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("address updated", zap.String("user_id", userID), zap.String("address_id", address.ID.Hex()))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(address)
+}
+
+func (h *UserHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.addressRepo.DeleteAddress(r.Context(), userID, id); err != nil {
+		h.logger.Warn("err deleting address", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	h.logger.Info("address deleted", zap.String("user_id", userID), zap.String("address_id", id))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("address deleted"))
+}
+
+// grpc handlers
+
+// GetAddress is used by the order service to snapshot an address at checkout.
+func (h *UserHandler) GetAddress(ctx context.Context, req *userpb.GetAddressRequest) (*userpb.GetAddressResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing user id")
+	}
+	if req.AddressId != "" && !primitive.IsValidObjectID(req.AddressId) {
+		return nil, status.Error(codes.InvalidArgument, "invalid address id")
+	}
+
+	var address *models.Address
+	var err error
+	switch {
+	case req.AddressId != "":
+		address, err = h.addressRepo.GetAddress(ctx, req.UserId, req.AddressId)
+	case req.Kind == userpb.AddressKind_ADDRESS_KIND_BILLING:
+		address, err = h.addressRepo.GetDefaultAddress(ctx, req.UserId, models.AddressBilling)
+	default:
+		address, err = h.addressRepo.GetDefaultAddress(ctx, req.UserId, models.AddressShipping)
+	}
+	if err != nil {
+		h.logger.Error("db error fetching address", zap.Error(err), zap.String("user_id", req.UserId))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if address == nil {
+		return nil, status.Error(codes.NotFound, "address not found")
+	}
+
+	return &userpb.GetAddressResponse{
+		Address: &userpb.Address{
+			Id:              address.ID.Hex(),
+			UserId:          address.UserID,
+			FullName:        address.FullName,
+			Line1:           address.Line1,
+			Line2:           address.Line2,
+			City:            address.City,
+			Region:          address.Region,
+			PostalCode:      address.PostalCode,
+			Country:         address.Country,
+			Phone:           address.Phone,
+			DefaultShipping: address.DefaultShipping,
+			DefaultBilling:  address.DefaultBilling,
+		},
+	}, nil
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"grpc_module/auth/authpb"
+	"user-service/internal/database"
+	"user-service/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// authAdmin is authUser plus a role check, the role comes from the token.
+func (h *UserHandler) authAdmin(w http.ResponseWriter, r *http.Request, roles ...models.Role) (string, bool) {
+	resp, ok := h.validateToken(w, r)
+	if !ok {
+		return "", false
+	}
+	for _, role := range roles {
+		if models.Role(resp.Role) == role {
+			return resp.UserId, true
+		}
+	}
+	h.logger.Warn("admin route hit without the right role",
+		zap.String("user_id", resp.UserId),
+		zap.String("role", resp.Role),
+		zap.String("path", r.URL.Path),
+	)
+	http.Error(w, "forbidden", http.StatusForbidden)
+	return "", false
+}
+
+// pagination reads ?page=&limit=, page starts at 1.
+func pagination(r *http.Request) (page, limit int64) {
+	page, _ = strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return page, limit
+}
+
+func adminUserResponse(user *models.User) map[string]any {
+	resp := profileResponse(user)
+	resp["role"] = user.GetRole()
+	resp["status"] = user.GetStatus()
+	resp["updated_at"] = user.UpdatedAt
+	if user.DisabledReason != "" {
+		resp["disabled_reason"] = user.DisabledReason
+	}
+	return resp
+}
+
+func (h *UserHandler) AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r, models.RoleAdmin, models.RoleSupport); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	page, limit := pagination(r)
+	filter := database.UserFilter{
+		Email:  q.Get("email"),
+		Role:   models.Role(q.Get("role")),
+		Status: models.UserStatus(q.Get("status")),
+		Limit:  limit,
+		Skip:   (page - 1) * limit,
+	}
+	if filter.Role != "" && !filter.Role.Valid() {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+	if filter.Status != "" && filter.Status != models.StatusActive && filter.Status != models.StatusDisabled {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	for param, dst := range map[string]*time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, param+" must be RFC3339", http.StatusBadRequest)
+			return
+		}
+		*dst = t
+	}
+
+	users, total, err := h.userRepo.ListUsers(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("err listing users", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]map[string]any, 0, len(users))
+	for _, user := range users {
+		items = append(items, adminUserResponse(user))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"users": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (h *UserHandler) AdminGetUser(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r, models.RoleAdmin, models.RoleSupport); !ok {
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminUserResponse(user))
+}
+
+func (h *UserHandler) AdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	targetID := user.ID.Hex()
+	if targetID == actorID {
+		http.Error(w, "can't disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userRepo.SetStatus(r.Context(), targetID, models.StatusDisabled, req.Reason); err != nil {
+		h.logger.Error("err disabling user", zap.Error(err), zap.String("user_id", targetID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, actorID, models.AuditDisableUser, targetID, map[string]string{"reason": req.Reason})
+	// status is saved, a failed revoke can just be retried
+	if !h.revokeTokens(w, r, targetID, true) {
+		return
+	}
+
+	h.logger.Info("user disabled", zap.String("user_id", targetID), zap.String("actor_id", actorID))
+	user.Status = models.StatusDisabled
+	user.DisabledReason = req.Reason
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminUserResponse(user))
+}
+
+func (h *UserHandler) AdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	targetID := user.ID.Hex()
+
+	if err := h.userRepo.SetStatus(r.Context(), targetID, models.StatusActive, ""); err != nil {
+		h.logger.Error("err enabling user", zap.Error(err), zap.String("user_id", targetID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, actorID, models.AuditEnableUser, targetID, nil)
+	if !h.revokeTokens(w, r, targetID, false) {
+		return
+	}
+
+	h.logger.Info("user enabled", zap.String("user_id", targetID), zap.String("actor_id", actorID))
+	user.Status = models.StatusActive
+	user.DisabledReason = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminUserResponse(user))
+}
+
+func (h *UserHandler) AdminSetRole(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := h.authAdmin(w, r, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Role.Valid() {
+		http.Error(w, "role must be one of customer, support, admin", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	targetID := user.ID.Hex()
+	if targetID == actorID {
+		http.Error(w, "can't change your own role", http.StatusBadRequest)
+		return
+	}
+	previous := user.GetRole()
+	if previous == req.Role {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(adminUserResponse(user))
+		return
+	}
+
+	if err := h.userRepo.SetRole(r.Context(), targetID, req.Role); err != nil {
+		h.logger.Error("err setting role", zap.Error(err), zap.String("user_id", targetID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, actorID, models.AuditSetRole, targetID, map[string]string{
+		"from": string(previous),
+		"to":   string(req.Role),
+	})
+	// the role is baked into the token, make them log in again to pick it up
+	if user.GetStatus() == models.StatusActive && !h.revokeTokens(w, r, targetID, false) {
+		return
+	}
+
+	h.logger.Info("user role changed",
+		zap.String("user_id", targetID),
+		zap.String("actor_id", actorID),
+		zap.String("role", string(req.Role)),
+	)
+	user.Role = req.Role
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminUserResponse(user))
+}
+
+func (h *UserHandler) AdminListAudit(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authAdmin(w, r, models.RoleAdmin); !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	q := r.URL.Query()
+	entries, err := h.auditRepo.ListEntries(r.Context(), q.Get("actor_id"), q.Get("target_id"), limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error("err listing audit entries", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"entries": entries,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *UserHandler) targetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := h.userRepo.GetUserById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.logger.Warn("err fetching user", zap.Error(err))
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *UserHandler) revokeTokens(w http.ResponseWriter, r *http.Request, userID string, disabled bool) bool {
+	_, err := h.authClient.RevokeUser(r.Context(), &authpb.RevokeUserRequest{UserId: userID, Disabled: disabled})
+	if err != nil {
+		h.logger.Error("auth service revoke failure", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "saved, but couldnt revoke sessions, retry", http.StatusBadGateway)
+		return false
+	}
+	return true
+}
+
+// audit failures are logged loudly but don't undo the action
+func (h *UserHandler) audit(r *http.Request, actorID string, action models.AuditAction, targetID string, details map[string]string) {
+	entry := &models.AuditEntry{
+		ActorID:  actorID,
+		Action:   action,
+		TargetID: targetID,
+		Details:  details,
+	}
+	if err := h.auditRepo.Record(r.Context(), entry); err != nil {
+		h.logger.Error("failed to write audit entry",
+			zap.Error(err),
+			zap.String("actor_id", actorID),
+			zap.String("action", string(action)),
+			zap.String("target_id", targetID),
+			zap.Any("details", details),
+		)
+	}
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"grpc_module/user/userpb"
+	"user-service/internal/database"
+	"user-service/internal/models"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type preferencesRequest struct {
+	MarketingOptIn *bool                            `json:"marketing_opt_in"`
+	Channels       map[string]models.ChannelToggles `json:"channels"`
+	Locale         *string                          `json:"locale"`
+	Currency       *string                          `json:"currency"`
+}
+
+func (h *UserHandler) GetPreferencesHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.loadPreferences(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err fetching preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdatePreferences only touches the fields sent, channels are merged per event type.
+func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req preferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err in decoding json", zap.Error(err))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.loadPreferences(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err fetching preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if req.MarketingOptIn != nil {
+		prefs.MarketingOptIn = *req.MarketingOptIn
+	}
+	for event, toggles := range req.Channels {
+		prefs.Channels[event] = toggles
+	}
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+	if req.Currency != nil {
+		prefs.Currency = *req.Currency
+	}
+	if err := prefs.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := fillUnsubscribeToken(prefs); err != nil {
+		h.logger.Error("err generating unsubscribe token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.preferencesRepo.SavePreferences(r.Context(), prefs); err != nil {
+		h.logger.Error("err saving preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("preferences updated", zap.String("user_id", userID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// ResetPreferences goes back to the defaults, the unsubscribe token is kept so
+// links in mails already sent keep working.
+func (h *UserHandler) ResetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.preferencesRepo.GetPreferences(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err fetching preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	prefs := models.DefaultPreferences(userID)
+	if current != nil {
+		prefs.UnsubscribeToken = current.UnsubscribeToken
+	}
+	if err := fillUnsubscribeToken(prefs); err != nil {
+		h.logger.Error("err generating unsubscribe token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.preferencesRepo.SavePreferences(r.Context(), prefs); err != nil {
+		h.logger.Error("err saving preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("preferences reset", zap.String("user_id", userID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// Unsubscribe is hit straight from mails, the token is the auth.
+// GET only shows a confirm button since link scanners follow GETs, POST does
+// the work and is also what mail clients send for one-click (RFC 8058).
+func (h *UserHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	kind := r.URL.Query().Get("type")
+	if kind == "" {
+		kind = "all"
+	}
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<form method="post" action="?token=%s&amp;type=%s"><button type="submit">Unsubscribe</button></form>`,
+			html.EscapeString(token), html.EscapeString(kind))
+		return
+	}
+
+	prefs, err := h.preferencesRepo.GetPreferencesByUnsubscribeToken(r.Context(), token)
+	if err != nil {
+		h.logger.Error("err fetching preferences by token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if prefs == nil {
+		http.Error(w, "invalid or expired link", http.StatusNotFound)
+		return
+	}
+	fillDefaultChannels(prefs)
+	if err := prefs.Unsubscribe(kind); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.preferencesRepo.SavePreferences(r.Context(), prefs); err != nil {
+		h.logger.Error("err saving preferences", zap.Error(err), zap.String("user_id", prefs.UserID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("unsubscribed", zap.String("user_id", prefs.UserID), zap.String("type", kind))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("you have been unsubscribed"))
+}
+
+// loadPreferences returns the saved preferences or the defaults, never nil.
+func (h *UserHandler) loadPreferences(ctx context.Context, userID string) (*models.Preferences, error) {
+	prefs, err := h.preferencesRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return models.DefaultPreferences(userID), nil
+	}
+	fillDefaultChannels(prefs)
+	return prefs, nil
+}
+
+// event types added after the user saved get the default toggles
+func fillDefaultChannels(prefs *models.Preferences) {
+	defaults := models.DefaultPreferences(prefs.UserID)
+	if prefs.Channels == nil {
+		prefs.Channels = defaults.Channels
+		return
+	}
+	for event, toggles := range defaults.Channels {
+		if _, ok := prefs.Channels[event]; !ok {
+			prefs.Channels[event] = toggles
+		}
+	}
+}
+
+func fillUnsubscribeToken(prefs *models.Preferences) error {
+	if prefs.UnsubscribeToken != "" {
+		return nil
+	}
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	prefs.UnsubscribeToken = token
+	return nil
+}
+
+// ensureUnsubscribeToken gives a user a token without touching their other
+// preferences.
+func (h *UserHandler) ensureUnsubscribeToken(ctx context.Context, userID string) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	return h.preferencesRepo.EnsureUnsubscribeToken(ctx, userID, token)
+}
+
+// BackfillUnsubscribeTokens gives every account created before tokens were
+// handed out on register one, so the read path never has to write.
+func (h *UserHandler) BackfillUnsubscribeTokens(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		ids, err := h.preferencesRepo.WithoutUnsubscribeToken(ctx, 500)
+		if err != nil {
+			return n, err
+		}
+		if len(ids) == 0 {
+			return n, nil
+		}
+		for _, id := range ids {
+			if err := h.ensureUnsubscribeToken(ctx, id); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
+
+// grpc handlers
+
+// GetPreferences is used by notification-service before sending anything
+// opt-out-able. It is read only, the unsubscribe token is created on register
+// and by the backfill, so it may still be empty for a moment on old accounts.
+func (h *UserHandler) GetPreferences(ctx context.Context, req *userpb.GetPreferencesRequest) (*userpb.GetPreferencesResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing user id")
+	}
+
+	// deleted accounts have no preferences to hand out
+	user, err := h.userRepo.GetUserById(ctx, req.UserId)
+	if errors.Is(err, database.ErrInvalidUserID) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		h.logger.Error("db error fetching user", zap.Error(err), zap.String("user_id", req.UserId))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	prefs, err := h.loadPreferences(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("db error fetching preferences", zap.Error(err), zap.String("user_id", req.UserId))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &userpb.Preferences{
+		UserId:           prefs.UserID,
+		MarketingOptIn:   prefs.MarketingOptIn,
+		Channels:         make(map[string]*userpb.ChannelToggles, len(prefs.Channels)),
+		Locale:           prefs.Locale,
+		Currency:         prefs.Currency,
+		UnsubscribeToken: prefs.UnsubscribeToken,
+	}
+	for event, toggles := range prefs.Channels {
+		resp.Channels[event] = &userpb.ChannelToggles{Email: toggles.Email, Sms: toggles.SMS}
+	}
+	return &userpb.GetPreferencesResponse{Preferences: resp}, nil
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"user-service/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// StartExport kicks off a data export. Our own part is written straight away,
+// the other services answer on the privacy topic and the job completes once all have.
+func (h *UserHandler) StartExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetUserById(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to fetch user", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	addresses, err := h.addressRepo.ListAddresses(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err listing addresses", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	prefs, err := h.loadPreferences(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("err fetching preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	part, err := json.Marshal(map[string]any{
+		"profile":     profileResponse(user),
+		"addresses":   addresses,
+		"preferences": prefs,
+	})
+	if err != nil {
+		h.logger.Error("err marshalling export", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	job := &models.PrivacyJob{
+		UserID:  userID,
+		Kind:    models.PrivacyExport,
+		Pending: append([]string{}, h.privacyServices...),
+		Parts:   map[string]string{"user-service": string(part)},
+	}
+	if err := h.privacyRepo.CreateJob(r.Context(), job); err != nil {
+		h.logger.Error("err creating export job", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	event := models.UserExportRequestedEvent{
+		JobID:  job.ID.Hex(),
+		UserID: userID,
+		Time:   time.Now(),
+	}
+	if err := h.userProducer.PublishUserExportRequested(r.Context(), event); err != nil {
+		h.logger.Error("failed to publish user-export-requested event", zap.Error(err), zap.String("job_id", job.ID.Hex()))
+		// no service heard of the job, left pending it would never finish
+		if err := h.privacyRepo.FailJob(r.Context(), job.ID, "couldnt reach the other services"); err != nil {
+			h.logger.Error("err failing export job", zap.Error(err), zap.String("job_id", job.ID.Hex()))
+		}
+		http.Error(w, "couldnt start export, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.logger.Info("export started", zap.String("user_id", userID), zap.String("job_id", job.ID.Hex()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *UserHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.exportJob(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *UserHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.exportJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status == models.PrivacyFailed {
+		http.Error(w, "export failed, start a new one", http.StatusConflict)
+		return
+	}
+	if job.Status != models.PrivacyCompleted {
+		http.Error(w, "export is still running", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.json"`, job.ID.Hex()))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(job.Archive())
+}
+
+func (h *UserHandler) exportJob(w http.ResponseWriter, r *http.Request) (*models.PrivacyJob, bool) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.privacyRepo.GetJob(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		h.logger.Warn("err fetching export job", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "invalid export id", http.StatusBadRequest)
+		return nil, false
+	}
+	if job == nil || job.Kind != models.PrivacyExport {
+		http.Error(w, "export not found", http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
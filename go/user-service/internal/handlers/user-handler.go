@@ -2,9 +2,14 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"grpc_module/auth/authpb"
@@ -20,27 +25,58 @@ import (
 
 type UserHandler struct {
 	userpb.UnimplementedUserServiceServer
-	userRepo     database.UserRepository
-	logger       *zap.Logger
-	userProducer *kafka.UserProducer
-	authClient   authpb.AuthServiceClient
+	userRepo        database.UserRepository
+	addressRepo     database.AddressRepository
+	privacyRepo     database.PrivacyJobRepository
+	auditRepo       database.AuditRepository
+	preferencesRepo database.PreferencesRepository
+	logger          *zap.Logger
+	userProducer    *kafka.UserProducer
+	authClient      authpb.AuthServiceClient
+	// other services holding user data, each has to answer export/erasure jobs
+	privacyServices []string
 }
 
-func NewUserHandler(userRepo database.UserRepository, logger *zap.Logger, authClient authpb.AuthServiceClient, userProducer *kafka.UserProducer) *UserHandler {
+func NewUserHandler(userRepo database.UserRepository, addressRepo database.AddressRepository, privacyRepo database.PrivacyJobRepository, auditRepo database.AuditRepository, preferencesRepo database.PreferencesRepository, logger *zap.Logger, authClient authpb.AuthServiceClient, userProducer *kafka.UserProducer, privacyServices []string) *UserHandler {
 	return &UserHandler{
-		userRepo:   userRepo,
-		logger:     logger,
-		authClient: authClient,
-		userProducer: kafka.NewUserProducer(
-			[]string{"localhost:9092"},
-			"user-events",
-		),
+		userRepo:        userRepo,
+		addressRepo:     addressRepo,
+		privacyRepo:     privacyRepo,
+		auditRepo:       auditRepo,
+		preferencesRepo: preferencesRepo,
+		logger:          logger,
+		authClient:      authClient,
+		privacyServices: privacyServices,
+		userProducer:    userProducer,
 	}
 }
 
 func (h *UserHandler) Routes() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/profile", h.Profile)
+	mux.HandleFunc("GET /profile", h.Profile)
+	mux.HandleFunc("PUT /profile", h.UpdateProfile)
+	mux.HandleFunc("DELETE /profile", h.DeleteProfile)
+	mux.HandleFunc("POST /profile/password", h.ChangePassword)
+	mux.HandleFunc("GET /profile/verify-email", h.VerifyEmail)
+	mux.HandleFunc("GET /profile/addresses", h.ListAddresses)
+	mux.HandleFunc("POST /profile/addresses", h.CreateAddress)
+	mux.HandleFunc("GET /profile/addresses/{id}", h.GetAddressHTTP)
+	mux.HandleFunc("PUT /profile/addresses/{id}", h.UpdateAddress)
+	mux.HandleFunc("DELETE /profile/addresses/{id}", h.DeleteAddress)
+	mux.HandleFunc("GET /profile/preferences", h.GetPreferencesHTTP)
+	mux.HandleFunc("PUT /profile/preferences", h.UpdatePreferences)
+	mux.HandleFunc("DELETE /profile/preferences", h.ResetPreferences)
+	mux.HandleFunc("GET /unsubscribe", h.Unsubscribe)
+	mux.HandleFunc("POST /unsubscribe", h.Unsubscribe)
+	mux.HandleFunc("POST /profile/export", h.StartExport)
+	mux.HandleFunc("GET /profile/export/{id}", h.GetExport)
+	mux.HandleFunc("GET /profile/export/{id}/download", h.DownloadExport)
+	mux.HandleFunc("GET /admin/users", h.AdminListUsers)
+	mux.HandleFunc("GET /admin/users/{id}", h.AdminGetUser)
+	mux.HandleFunc("POST /admin/users/{id}/disable", h.AdminDisableUser)
+	mux.HandleFunc("POST /admin/users/{id}/enable", h.AdminEnableUser)
+	mux.HandleFunc("PUT /admin/users/{id}/role", h.AdminSetRole)
+	mux.HandleFunc("GET /admin/audit", h.AdminListAudit)
 	mux.HandleFunc("/register", h.Register)
 	mux.HandleFunc("/login", h.Login)
 	mux.HandleFunc("/logout", h.Logout)
@@ -82,6 +118,8 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: authResp.HashedPassword,
+		Role:     models.RoleCustomer,
+		Status:   models.StatusActive,
 	}
 	err = h.userRepo.CreateUser(r.Context(), user)
 	if err != nil {
@@ -89,6 +127,10 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("err creating user", zap.Error(err), zap.String("email", user.Email))
 		return
 	}
+	// the backfill picks the user up if this fails
+	if err := h.ensureUnsubscribeToken(r.Context(), user.ID.Hex()); err != nil {
+		h.logger.Error("err creating unsubscribe token", zap.Error(err), zap.String("user_id", user.ID.Hex()))
+	}
 
 	event := models.UserCreatedEvent{
 		ID:    user.ID.Hex(),
@@ -146,12 +188,18 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if user.GetStatus() == models.StatusDisabled {
+		h.logger.Warn("login attempt on disabled account", zap.String("user_id", user.ID.Hex()))
+		http.Error(w, "account disabled", http.StatusForbidden)
+		return
+	}
 
 	authResp, err := h.authClient.Authenticate(context.Background(), &authpb.AuthRequest{
 		UserId:         user.ID.Hex(),
 		Email:          req.Email,
 		Password:       req.Password,
 		HashedPassword: user.Password,
+		Role:           string(user.GetRole()),
 	})
 	if err != nil {
 		h.logger.Error("auth service grpc failure",
@@ -217,14 +265,345 @@ func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetUserById(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to fetch user profile", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusBadRequest)
+		return
+	}
+	if user == nil {
+		h.logger.Warn("profile for missing user", zap.String("user_id", userID))
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileResponse(user))
+	h.logger.Info("profile fetched",
+		zap.String("user_id", user.ID.Hex()),
+	)
+}
+
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name  *string `json:"name,omitempty"`
+		Email *string `json:"email,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err in decoding json", zap.Error(err))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.GetUserById(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to fetch user", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	var fields []string
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len(name) < 3 {
+			http.Error(w, "name must be at least 3 characters", http.StatusBadRequest)
+			return
+		}
+		if name != user.Name {
+			user.Name = name
+			if err := h.userRepo.UpdateUser(r.Context(), user); err != nil {
+				h.logger.Error("err updating user", zap.Error(err), zap.String("user_id", userID))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			fields = append(fields, "name")
+		}
+	}
+
+	// new email only takes effect once the verify link is clicked
+	var verification *models.EmailVerificationRequestedEvent
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if _, err := mail.ParseAddress(email); err != nil {
+			http.Error(w, "invalid email", http.StatusBadRequest)
+			return
+		}
+		if email != user.Email {
+			existing, err := h.userRepo.GetUserByEmail(r.Context(), email)
+			if err != nil {
+				h.logger.Error("db error while fetching user", zap.Error(err), zap.String("email", email))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				http.Error(w, "email already in use", http.StatusConflict)
+				return
+			}
+
+			token, err := newToken()
+			if err != nil {
+				h.logger.Error("err generating verify token", zap.Error(err))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			expires := time.Now().Add(emailVerifyTTL)
+			if err := h.userRepo.SetPendingEmail(r.Context(), userID, email, token, expires); err != nil {
+				h.logger.Error("err setting pending email", zap.Error(err), zap.String("user_id", userID))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			user.PendingEmail = email
+			fields = append(fields, "pending_email")
+			verification = &models.EmailVerificationRequestedEvent{
+				ID:        userID,
+				Name:      user.Name,
+				Email:     email,
+				Token:     token,
+				ExpiresAt: expires,
+				Time:      time.Now(),
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		http.Error(w, "no fields to update", http.StatusBadRequest)
+		return
+	}
+
+	event := models.UserUpdatedEvent{
+		ID:           userID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Fields:       fields,
+		PendingEmail: user.PendingEmail,
+		Time:         time.Now(),
+	}
+	if err := h.userProducer.PublishUserUpdated(r.Context(), event); err != nil {
+		h.logger.Error("failed to publish user-updated event", zap.Error(err))
+	}
+	if verification != nil {
+		if err := h.userProducer.PublishEmailVerificationRequested(r.Context(), *verification); err != nil {
+			h.logger.Error("failed to publish email-verification event", zap.Error(err))
+		}
+	}
+
+	h.logger.Info("profile updated", zap.String("user_id", userID), zap.Strings("fields", fields))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileResponse(user))
+}
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.ConfirmEmail(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, database.ErrEmailTaken) {
+			http.Error(w, "email already in use", http.StatusConflict)
+			return
+		}
+		h.logger.Error("err confirming email", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	event := models.UserUpdatedEvent{
+		ID:     user.ID.Hex(),
+		Email:  user.Email,
+		Name:   user.Name,
+		Fields: []string{"email"},
+		Time:   time.Now(),
+	}
+	if err := h.userProducer.PublishUserUpdated(r.Context(), event); err != nil {
+		h.logger.Error("failed to publish user-updated event", zap.Error(err))
+	}
+
+	h.logger.Info("email verified", zap.String("user_id", user.ID.Hex()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("email verified"))
+}
+
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("err in decoding json", zap.Error(err))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || len(req.NewPassword) < 6 {
+		http.Error(w, "current password and a new password of at least 6 characters are required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.checkPassword(w, r, userID, req.CurrentPassword)
+	if !ok {
+		return
+	}
+
+	hashResp, err := h.authClient.GeneratePassword(r.Context(), &authpb.BcryptPasswordRequest{Password: req.NewPassword})
+	if err != nil {
+		h.logger.Error("auth-service grpc err", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.userRepo.UpdatePassword(r.Context(), userID, hashResp.HashedPassword); err != nil {
+		h.logger.Error("err updating password", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	event := models.UserUpdatedEvent{
+		ID:     userID,
+		Email:  user.Email,
+		Name:   user.Name,
+		Fields: []string{"password"},
+		Time:   time.Now(),
+	}
+	if err := h.userProducer.PublishUserUpdated(r.Context(), event); err != nil {
+		h.logger.Error("failed to publish user-updated event", zap.Error(err))
+	}
+
+	h.logger.Info("password changed", zap.String("user_id", userID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password changed"))
+}
+
+func (h *UserHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "password is required to delete the account", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.checkPassword(w, r, userID, req.Password)
+	if !ok {
+		return
+	}
+
+	// the job outlives the account so erasure in the other services can be tracked
+	job := &models.PrivacyJob{
+		UserID:  userID,
+		Kind:    models.PrivacyErasure,
+		Pending: append([]string{}, h.privacyServices...),
+	}
+	if err := h.privacyRepo.CreateJob(r.Context(), job); err != nil {
+		h.logger.Error("err creating erasure job", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.addressRepo.DeleteAllAddresses(r.Context(), userID); err != nil {
+		h.logger.Error("err deleting addresses", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.preferencesRepo.DeletePreferences(r.Context(), userID); err != nil {
+		h.logger.Error("err deleting preferences", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.userRepo.DeleteUser(r.Context(), userID); err != nil {
+		h.logger.Error("err deleting user", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	event := models.UserDeletedEvent{
+		ID:    userID,
+		Email: user.Email,
+		Time:  time.Now(),
+	}
+	if err := h.userProducer.PublishUserDeleted(r.Context(), event); err != nil {
+		h.logger.Error("failed to publish user-deleted event", zap.Error(err))
+	}
+	deletionEvent := models.UserDeletionRequestedEvent{
+		JobID:  job.ID.Hex(),
+		UserID: userID,
+		Email:  user.Email,
+		Time:   time.Now(),
+	}
+	if err := h.userProducer.PublishUserDeletionRequested(r.Context(), deletionEvent); err != nil {
+		h.logger.Error("failed to publish user-deletion-requested event", zap.Error(err), zap.String("job_id", job.ID.Hex()))
+	}
+
+	// kill tokens held anywhere else too
+	if _, err := h.authClient.RevokeUser(r.Context(), &authpb.RevokeUserRequest{UserId: userID, Disabled: true}); err != nil {
+		h.logger.Error("failed to revoke tokens of deleted user", zap.Error(err), zap.String("user_id", userID))
+	}
+
+	// same as logout, the token is useless now anyway
+	http.SetCookie(w, &http.Cookie{
+		Name:     "Authorization",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+
+	h.logger.Info("user deleted", zap.String("user_id", userID), zap.String("erasure_job_id", job.ID.Hex()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("account deleted"))
+}
+
+// authUser validates the auth cookie and returns the caller's user id.
+// It writes the error response itself so callers just return when !ok.
+func (h *UserHandler) authUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	resp, ok := h.validateToken(w, r)
+	if !ok {
+		return "", false
+	}
+	return resp.UserId, true
+}
+
+func (h *UserHandler) validateToken(w http.ResponseWriter, r *http.Request) (*authpb.ValidateTokenResponse, bool) {
 	cookie, err := r.Cookie("Authorization")
 	if err != nil {
-		h.logger.Warn("missing authorization cookie")
+		h.logger.Warn("missing authorization cookie", zap.String("path", r.URL.Path))
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
-	resp, err := h.authClient.ValidateToken(context.Background(), &authpb.ValidateTokenRequest{
+	resp, err := h.authClient.ValidateToken(r.Context(), &authpb.ValidateTokenRequest{
 		Token: cookie.Value,
 	})
 	if err != nil {
@@ -232,27 +611,71 @@ func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
 			zap.Error(err),
 		)
 		http.Error(w, "internal server error", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 	if !resp.Valid {
 		h.logger.Warn("invalid token provided")
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
+	return resp, true
+}
 
-	user, err := h.userRepo.GetUserById(r.Context(), resp.UserId)
+// checkPassword re-checks the user's password with auth-service before
+// sensitive changes.
+func (h *UserHandler) checkPassword(w http.ResponseWriter, r *http.Request, userID, password string) (*models.User, bool) {
+	user, err := h.userRepo.GetUserById(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("failed to fetch user profile", zap.Error(err), zap.String("user_id", user.ID.Hex()))
-		http.Error(w, "internal server error", http.StatusBadRequest)
-		return
+		h.logger.Error("failed to fetch user", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return nil, false
 	}
-	json.NewEncoder(w).Encode(map[string]string{
-		"id":    user.ID.Hex(),
-		"email": user.Email,
+
+	authResp, err := h.authClient.Authenticate(r.Context(), &authpb.AuthRequest{
+		UserId:         userID,
+		Email:          user.Email,
+		Password:       password,
+		HashedPassword: user.Password,
 	})
-	h.logger.Info("profile fetched",
-		zap.String("user_id", user.ID.Hex()),
-	)
+	if err != nil {
+		h.logger.Error("auth service grpc failure", zap.Error(err), zap.String("user_id", userID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !authResp.Valid {
+		h.logger.Warn("wrong current password", zap.String("user_id", userID))
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+func profileResponse(user *models.User) map[string]any {
+	resp := map[string]any{
+		"id":         user.ID.Hex(),
+		"name":       user.Name,
+		"email":      user.Email,
+		"created_at": user.CreatedAt,
+	}
+	if user.PendingEmail != "" {
+		resp["pending_email"] = user.PendingEmail
+	}
+	return resp
+}
+
+// how long the link in an email change mail works
+const emailVerifyTTL = 24 * time.Hour
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // grpc handlers
@@ -276,11 +699,16 @@ func (h *UserHandler) VerifyCredentials(ctx context.Context, req *userpb.VerifyC
 			// Token: "",
 		}, nil
 	}
+	if user.GetStatus() == models.StatusDisabled {
+		h.logger.Warn("verifycreds attempt on disabled account", zap.String("user_id", user.ID.Hex()))
+		return &userpb.VerifyCredentialsResponse{Valid: false}, nil
+	}
 	authReq := &authpb.AuthRequest{
 		Email:          req.Email,
 		Password:       req.Password,
 		HashedPassword: user.Password,
 		UserId:         user.ID.Hex(),
+		Role:           string(user.GetRole()),
 	}
 
 	authResp, err := h.authClient.Authenticate(ctx, authReq)
@@ -301,3 +729,127 @@ func (h *UserHandler) VerifyCredentials(ctx context.Context, req *userpb.VerifyC
 		UserId: user.ID.Hex(),
 	}, nil
 }
+
+func (h *UserHandler) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing user id")
+	}
+
+	// a malformed id can't belong to anyone, callers treat it like a missing user
+	user, err := h.userRepo.GetUserById(ctx, req.UserId)
+	if errors.Is(err, database.ErrInvalidUserID) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		h.logger.Error("db error fetching user", zap.Error(err), zap.String("user_id", req.UserId))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return &userpb.GetUserResponse{User: userToProto(user)}, nil
+}
+
+const maxBatchGetUsers = 100
+
+func (h *UserHandler) BatchGetUsers(ctx context.Context, req *userpb.BatchGetUsersRequest) (*userpb.BatchGetUsersResponse, error) {
+	if len(req.UserIds) == 0 {
+		return &userpb.BatchGetUsersResponse{}, nil
+	}
+	if len(req.UserIds) > maxBatchGetUsers {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d user ids per call", maxBatchGetUsers)
+	}
+
+	users, err := h.userRepo.GetUsersByIds(ctx, req.UserIds)
+	if err != nil {
+		h.logger.Error("db error fetching users", zap.Error(err), zap.Int("count", len(req.UserIds)))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &userpb.BatchGetUsersResponse{Users: make([]*userpb.User, 0, len(users))}
+	found := make(map[string]bool, len(users))
+	for _, user := range users {
+		found[user.ID.Hex()] = true
+		resp.Users = append(resp.Users, userToProto(user))
+	}
+	for _, id := range req.UserIds {
+		if !found[id] {
+			found[id] = true // dedupe repeated ids
+			resp.NotFound = append(resp.NotFound, id)
+		}
+	}
+	return resp, nil
+}
+
+func (h *UserHandler) GetUserByEmail(ctx context.Context, req *userpb.GetUserByEmailRequest) (*userpb.GetUserByEmailResponse, error) {
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing email")
+	}
+
+	user, err := h.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		h.logger.Error("db error fetching user", zap.Error(err), zap.String("email", email))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return &userpb.GetUserByEmailResponse{User: userToProto(user)}, nil
+}
+
+const maxListUsersPageSize = 500
+
+func (h *UserHandler) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+	pageSize := int64(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	if pageSize > maxListUsersPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size can be at most %d", maxListUsersPageSize)
+	}
+	page := int64(req.Page)
+	if page < 1 {
+		page = 1
+	}
+
+	filter := database.UserFilter{
+		Email:  req.Email,
+		Role:   models.Role(req.Role),
+		Status: models.UserStatus(req.Status),
+		Limit:  pageSize,
+		Skip:   (page - 1) * pageSize,
+	}
+	if req.CreatedAfter > 0 {
+		filter.CreatedAfter = time.Unix(req.CreatedAfter, 0)
+	}
+	if req.CreatedBefore > 0 {
+		filter.CreatedBefore = time.Unix(req.CreatedBefore, 0)
+	}
+
+	users, total, err := h.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		h.logger.Error("db error listing users", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &userpb.ListUsersResponse{Total: total, Users: make([]*userpb.User, 0, len(users))}
+	for _, user := range users {
+		resp.Users = append(resp.Users, userToProto(user))
+	}
+	return resp, nil
+}
+
+func userToProto(user *models.User) *userpb.User {
+	return &userpb.User{
+		Id:        user.ID.Hex(),
+		Email:     user.Email,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+		Role:      string(user.GetRole()),
+		Status:    string(user.GetStatus()),
+	}
+}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"user-service/internal/database"
+	"user-service/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PrivacyConsumer reads the answers other services send back for export and
+// erasure jobs and ticks them off on the job.
+type PrivacyConsumer struct {
+	reader      *kafka.Reader
+	privacyRepo database.PrivacyJobRepository
+}
+
+func NewPrivacyConsumer(brokers []string, topic string, groupID string, repo database.PrivacyJobRepository) *PrivacyConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})
+	return &PrivacyConsumer{
+		reader:      reader,
+		privacyRepo: repo,
+	}
+}
+
+func (c *PrivacyConsumer) Consume(ctx context.Context) error {
+	log.Println("PrivacyConsumer started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("PrivacyConsumer stopping")
+			return nil
+		default:
+			msg, err := c.reader.FetchMessage(ctx)
+			if err != nil {
+				log.Println("fetch message err:", err)
+				continue
+			}
+
+			if err := c.ProcessMessage(ctx, msg); err != nil {
+				log.Println("process message err:", err)
+			} else if err := c.reader.CommitMessages(ctx, msg); err != nil {
+				log.Println("commit message err:", err)
+			}
+		}
+	}
+}
+
+func (c *PrivacyConsumer) ProcessMessage(ctx context.Context, msg kafka.Message) error {
+	var eventType string
+	for _, h := range msg.Headers {
+		if h.Key == "event" {
+			eventType = string(h.Value)
+			break
+		}
+	}
+
+	switch eventType {
+	case "UserDataExported":
+		var event models.UserDataExportedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.complete(ctx, event.JobID, event.Service, string(event.Data))
+	case "UserDataErased":
+		var event models.UserDataErasedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return err
+		}
+		return c.complete(ctx, event.JobID, event.Service, "")
+	default:
+		log.Println("Ignoring event", eventType)
+		return nil
+	}
+}
+
+func (c *PrivacyConsumer) complete(ctx context.Context, jobID, service, part string) error {
+	job, err := c.privacyRepo.CompleteService(ctx, jobID, service, part)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		// expired or bogus id, nothing to retry
+		log.Printf("privacy job %s not found, dropping answer from %s", jobID, service)
+		return nil
+	}
+	log.Printf("privacy job %s: %s done, waiting on %v", jobID, service, job.Pending)
+	if job.Status == models.PrivacyCompleted {
+		log.Printf("privacy job %s (%s) completed", jobID, job.Kind)
+	}
+	return nil
+}
+
+func (c *PrivacyConsumer) Close() error {
+	return c.reader.Close()
+}
@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,10 +14,13 @@ import (
 
 type UserProducer struct {
 	writer *kafka.Writer
-	topic  string
+	// verification links are secrets, they go to their own topic that only
+	// notification-service reads
+	verifyWriter *kafka.Writer
+	topic        string
 }
 
-func NewUserProducer(brokers []string, topic string) *UserProducer {
+func NewUserProducer(brokers []string, topic, verificationTopic string) *UserProducer {
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(brokers...),
 		Topic:        topic,
@@ -24,9 +28,17 @@ func NewUserProducer(brokers []string, topic string) *UserProducer {
 		RequiredAcks: kafka.RequireAll,
 		Async:        false,
 	}
+	verifyWriter := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        verificationTopic,
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireAll,
+		Async:        false,
+	}
 	return &UserProducer{
-		writer: writer,
-		topic:  topic,
+		writer:       writer,
+		verifyWriter: verifyWriter,
+		topic:        topic,
 	}
 }
 
@@ -57,6 +69,57 @@ func (p *UserProducer) PublishUserCreated(ctx context.Context, event models.User
 	return nil
 }
 
+func (p *UserProducer) PublishUserUpdated(ctx context.Context, event models.UserUpdatedEvent) error {
+	return p.publish(ctx, event.ID, "UserUpdated", event)
+}
+
+func (p *UserProducer) PublishEmailVerificationRequested(ctx context.Context, event models.EmailVerificationRequestedEvent) error {
+	return p.publishTo(ctx, p.verifyWriter, event.ID, "EmailVerificationRequested", event)
+}
+
+func (p *UserProducer) PublishUserDeleted(ctx context.Context, event models.UserDeletedEvent) error {
+	return p.publish(ctx, event.ID, "UserDeleted", event)
+}
+
+func (p *UserProducer) PublishUserExportRequested(ctx context.Context, event models.UserExportRequestedEvent) error {
+	return p.publish(ctx, event.UserID, "UserExportRequested", event)
+}
+
+func (p *UserProducer) PublishUserDeletionRequested(ctx context.Context, event models.UserDeletionRequestedEvent) error {
+	return p.publish(ctx, event.UserID, "UserDeletionRequested", event)
+}
+
+func (p *UserProducer) publish(ctx context.Context, key, eventName string, event any) error {
+	return p.publishTo(ctx, p.writer, key, eventName, event)
+}
+
+func (p *UserProducer) publishTo(ctx context.Context, writer *kafka.Writer, key, eventName string, event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventName, err)
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(key),
+		Value: data,
+		Headers: []kafka.Header{
+			{
+				Key:   "event",
+				Value: []byte(eventName),
+			},
+		},
+		Time: time.Now(),
+	}
+
+	if err := writer.WriteMessages(ctx, msg); err != nil {
+		log.Printf("failed to write %s event: %v", eventName, err)
+		return err
+	}
+
+	log.Printf("%s event published: %s", eventName, key)
+	return nil
+}
+
 func (p *UserProducer) Close() error {
-	return p.writer.Close()
+	return errors.Join(p.writer.Close(), p.verifyWriter.Close())
 }
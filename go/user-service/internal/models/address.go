@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Address struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"-"`
+	FullName        string             `bson:"full_name" json:"full_name"`
+	Line1           string             `bson:"line1" json:"line1"`
+	Line2           string             `bson:"line2,omitempty" json:"line2,omitempty"`
+	City            string             `bson:"city" json:"city"`
+	Region          string             `bson:"region,omitempty" json:"region,omitempty"` // state / province / county
+	PostalCode      string             `bson:"postal_code,omitempty" json:"postal_code,omitempty"`
+	Country         string             `bson:"country" json:"country"` // ISO 3166-1 alpha-2
+	Phone           string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	DefaultShipping bool               `bson:"default_shipping" json:"default_shipping"`
+	DefaultBilling  bool               `bson:"default_billing" json:"default_billing"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type AddressKind string
+
+const (
+	AddressShipping AddressKind = "shipping"
+	AddressBilling  AddressKind = "billing"
+)
+
+// postal code formats for the countries we ship to, nil = country has no postal codes
+var postalFormats = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"AE": nil,
+	"HK": nil,
+}
+
+// Normalize trims everything and upper-cases country/postal code so they
+// match the formats above.
+func (a *Address) Normalize() {
+	a.FullName = strings.TrimSpace(a.FullName)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Phone = strings.TrimSpace(a.Phone)
+}
+
+func (a *Address) Validate() error {
+	if a.FullName == "" || a.Line1 == "" || a.City == "" || a.Country == "" {
+		return errors.New("full_name, line1, city and country are required")
+	}
+	format, ok := postalFormats[a.Country]
+	if !ok {
+		return fmt.Errorf("unsupported country: %s", a.Country)
+	}
+	if format == nil {
+		return nil
+	}
+	if !format.MatchString(a.PostalCode) {
+		return fmt.Errorf("invalid postal code for %s", a.Country)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditDisableUser AuditAction = "user.disable"
+	AuditEnableUser  AuditAction = "user.enable"
+	AuditSetRole     AuditAction = "user.set_role"
+)
+
+// AuditEntry is one admin action, written once the action went through and never updated.
+type AuditEntry struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ActorID  string             `bson:"actor_id" json:"actor_id"`
+	Action   AuditAction        `bson:"action" json:"action"`
+	TargetID string             `bson:"target_id" json:"target_id"`
+	Details  map[string]string  `bson:"details,omitempty" json:"details,omitempty"`
+	Time     time.Time          `bson:"time" json:"time"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// NotificationEvents are the mails a user can switch off. Account mails
+// (welcome, email verification) always go out.
+var NotificationEvents = []string{
+	"OrderCreated",
+	"OrderShipped",
+	"PaymentCaptured",
+	"PaymentFailed",
+}
+
+type ChannelToggles struct {
+	Email bool `bson:"email" json:"email"`
+	SMS   bool `bson:"sms" json:"sms"`
+}
+
+// Preferences is one document per user, _id is the user id.
+type Preferences struct {
+	UserID         string                    `bson:"_id" json:"-"`
+	MarketingOptIn bool                      `bson:"marketing_opt_in" json:"marketing_opt_in"`
+	Channels       map[string]ChannelToggles `bson:"channels" json:"channels"`
+	Locale         string                    `bson:"locale" json:"locale"`
+	Currency       string                    `bson:"currency" json:"currency"`
+	// lets unsubscribe links work without logging in
+	UnsubscribeToken string    `bson:"unsubscribe_token,omitempty" json:"-"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultPreferences is what users get until they save their own:
+// no marketing, email on for everything.
+func DefaultPreferences(userID string) *Preferences {
+	p := &Preferences{
+		UserID:   userID,
+		Channels: make(map[string]ChannelToggles, len(NotificationEvents)),
+		Locale:   "en",
+		Currency: "USD",
+	}
+	for _, event := range NotificationEvents {
+		p.Channels[event] = ChannelToggles{Email: true}
+	}
+	return p
+}
+
+var (
+	localeFormat   = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+	currencyFormat = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+func (p *Preferences) Validate() error {
+	if !localeFormat.MatchString(p.Locale) {
+		return fmt.Errorf("invalid locale: %s", p.Locale)
+	}
+	if !currencyFormat.MatchString(p.Currency) {
+		return fmt.Errorf("invalid currency: %s", p.Currency)
+	}
+	for event := range p.Channels {
+		if !isNotificationEvent(event) {
+			return fmt.Errorf("unknown event type: %s", event)
+		}
+	}
+	return nil
+}
+
+// Unsubscribe turns email off for one event type, "marketing" or "all".
+func (p *Preferences) Unsubscribe(kind string) error {
+	switch {
+	case kind == "marketing":
+		p.MarketingOptIn = false
+	case kind == "all":
+		p.MarketingOptIn = false
+		for _, event := range NotificationEvents {
+			toggles := p.Channels[event]
+			toggles.Email = false
+			p.Channels[event] = toggles
+		}
+	case isNotificationEvent(kind):
+		toggles := p.Channels[kind]
+		toggles.Email = false
+		p.Channels[kind] = toggles
+	default:
+		return fmt.Errorf("unknown unsubscribe type: %s", kind)
+	}
+	return nil
+}
+
+func isNotificationEvent(event string) bool {
+	for _, e := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PrivacyJobKind string
+
+const (
+	PrivacyExport  PrivacyJobKind = "export"
+	PrivacyErasure PrivacyJobKind = "erasure"
+)
+
+type PrivacyJobStatus string
+
+const (
+	PrivacyPending   PrivacyJobStatus = "pending"
+	PrivacyCompleted PrivacyJobStatus = "completed"
+	// the request never reached the other services, nothing will answer
+	PrivacyFailed PrivacyJobStatus = "failed"
+)
+
+// PrivacyJob tracks an export or erasure across every service holding user data.
+// Pending lists the services that haven't reported back yet.
+type PrivacyJob struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID  string             `bson:"user_id" json:"-"`
+	Kind    PrivacyJobKind     `bson:"kind" json:"kind"`
+	Status  PrivacyJobStatus   `bson:"status" json:"status"`
+	Pending []string           `bson:"pending" json:"pending"`
+	Error   string             `bson:"error,omitempty" json:"error,omitempty"`
+	// export only, service name -> json blob it sent back
+	Parts       map[string]string `bson:"parts,omitempty" json:"-"`
+	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
+	CompletedAt time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	// exports are deleted by a ttl index once this passes, erasure records are kept
+	ExpiresAt time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// Archive is what the user downloads, one key per service.
+func (j *PrivacyJob) Archive() map[string]json.RawMessage {
+	archive := make(map[string]json.RawMessage, len(j.Parts))
+	for service, part := range j.Parts {
+		archive[service] = json.RawMessage(part)
+	}
+	return archive
+}
+
+type UserExportRequestedEvent struct {
+	JobID  string    `json:"job_id"`
+	UserID string    `json:"user_id"`
+	Time   time.Time `json:"time"`
+}
+type UserDeletionRequestedEvent struct {
+	JobID  string    `json:"job_id"`
+	UserID string    `json:"user_id"`
+	Email  string    `json:"email"`
+	Time   time.Time `json:"time"`
+}
+
+// sent back by each service on the privacy topic
+type UserDataExportedEvent struct {
+	JobID   string          `json:"job_id"`
+	UserID  string          `json:"user_id"`
+	Service string          `json:"service"`
+	Data    json.RawMessage `json:"data"`
+	Time    time.Time       `json:"time"`
+}
+type UserDataErasedEvent struct {
+	JobID   string    `json:"job_id"`
+	UserID  string    `json:"user_id"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+}
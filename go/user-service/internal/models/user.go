@@ -13,10 +13,78 @@ type User struct {
 	Name      string             `bson:"name" json:"name" validate:"required,min=3"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	// email change waiting for the user to click the verification link
+	PendingEmail       string    `bson:"pending_email,omitempty" json:"pending_email,omitempty"`
+	EmailVerifyToken   string    `bson:"email_verify_token,omitempty" json:"-"`
+	EmailVerifyExpires time.Time `bson:"email_verify_expires,omitempty" json:"-"`
+	// empty on accounts created before roles existed, use GetRole/GetStatus
+	Role           Role       `bson:"role,omitempty" json:"role"`
+	Status         UserStatus `bson:"status,omitempty" json:"status"`
+	DisabledReason string     `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
 }
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleSupport  Role = "support"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleCustomer || r == RoleSupport || r == RoleAdmin
+}
+
+type UserStatus string
+
+const (
+	StatusActive   UserStatus = "active"
+	StatusDisabled UserStatus = "disabled"
+)
+
+func (u *User) GetRole() Role {
+	if u.Role == "" {
+		return RoleCustomer
+	}
+	return u.Role
+}
+
+func (u *User) GetStatus() UserStatus {
+	if u.Status == "" {
+		return StatusActive
+	}
+	return u.Status
+}
+
 type UserCreatedEvent struct {
 	ID    string    `json:"id"`
 	Email string    `json:"email"`
 	Name  string    `json:"name"`
 	Time  time.Time `json:"time"`
 }
+type UserUpdatedEvent struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	// which of name, email, pending_email, password changed
+	Fields       []string  `json:"fields"`
+	PendingEmail string    `json:"pending_email,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// EmailVerificationRequestedEvent carries the verify link token, so it only
+// goes to the verification topic notification-service reads, never to
+// user-events.
+type EmailVerificationRequestedEvent struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Time      time.Time `json:"time"`
+}
+type UserDeletedEvent struct {
+	ID    string    `json:"id"`
+	Email string    `json:"email"`
+	Time  time.Time `json:"time"`
+}
@@ -7,20 +7,42 @@ const UPLOADS = import.meta.env.VITE_IMG_UPLOADS;
 const ProductCard = ({ product }) => {
   const { addToCart } = useCart();
   const [showModal, setShowModal] = useState(false);
+  const [variantId, setVariantId] = useState(product?.variants?.[0]?.id);
 
   if (!product) return <p>No product data</p>;
-  const { image, name, description, price } = product;
-  const imgSrc = image.startsWith("uploads/") ? image.slice(8) : image;
+  const { image, images, name, description, variants } = product;
+  const variant = variants?.find((v) => v.id === variantId);
+  const price = variant ? variant.price : product.price;
+  // new uploads store the full url, old ones a path under uploads/
+  const imgSrc = image.startsWith("http") ? image : `${UPLOADS}${image.replace(/^uploads\//, "")}`;
+  // resized renditions, products from before they existed only have image
+  const card = images?.card;
   return (
     <>
       <div onClick={() => setShowModal(true)} className=" w-72 cursor-pointer border-1 border-black backdrop-blur-xl rounded-md p-4  shadow-2xl transition-transform duration-300">
-        <img src={`${UPLOADS}${imgSrc}`} alt={name} className="w-full h-60 rounded-2xl shadow-lg object-cover mb-4" />
+        <picture>
+          {card && <source srcSet={card.webp} type="image/webp" />}
+          <img src={card ? card.jpeg : imgSrc} alt={name} className="w-full h-60 rounded-2xl shadow-lg object-cover mb-4" />
+        </picture>
         <h3 className="text-lg font-bold mb-1">Name: {name}</h3>
         <p className="text-black mb-1">Description: {description}</p>
-        <p className="text-black mb-1">Price: {price}</p>
+        <p className="text-black mb-1">
+          Price: {price}
+          {/* was/now during a sale, sales are on the product price only */}
+          {!variant && product.compare_at_price > 0 && <s className="text-gray-500 ml-2">{product.compare_at_price}</s>}
+        </p>
+        {variants?.length > 0 && (
+          <select value={variantId} onClick={(e) => e.stopPropagation()} onChange={(e) => setVariantId(e.target.value)} className="w-full p-2 rounded-lg mt-2">
+            {variants.map((v) => (
+              <option key={v.id} value={v.id} disabled={v.stock.available <= 0}>
+                {Object.values(v.options).join(" / ")}
+              </option>
+            ))}
+          </select>
+        )}
         <button onClick={(e) => {
           e.stopPropagation();
-          addToCart(product);
+          addToCart(product, variantId);
         }} className="bg-blue-500 p-2 w-full rounded-lg transition shadow-lg duration-200 ease-in-out transform hover:scale-[1.05] mt-2">Add to cart</button>
       </div>
 
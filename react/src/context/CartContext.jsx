@@ -6,6 +6,22 @@ const CartContext = createContext();
 
 export const useCart = () => useContext(CartContext);
 
+// same shape as the items the cart service returns, the variant's option map
+// and price rather than the product's option list
+const cartLine = (product, variantId) => {
+    const variant = product.variants?.find((v) => v.id === variantId);
+    return {
+        product_id: product.id,
+        variant_id: variantId,
+        sku: variant?.sku ?? product.sku,
+        options: variant?.options,
+        name: product.name,
+        image: variant?.image || product.image,
+        price_cents: variant?.price ?? product.price,
+        quantity: 1,
+    };
+};
+
 export const CartProvider = ({ children }) => {
     const [cart, setCart] = useState(null);
     const [cartItems, setCartItems] = useState([]);
@@ -14,27 +30,27 @@ export const CartProvider = ({ children }) => {
 
     const { user } = useAuth();
 
-    const addToCart = async (product) => {
+    const addToCart = async (product, variantId) => {
         if (!user?.id) return;
 
         try {
-            await api.post(`/cart/add`, { product_id: product.id });
+            await api.post(`/cart/add`, { product_id: product.id, variant_id: variantId });
 
             setCartItems((prev) => {
-                if (prev.some((item) => item.product_id === product.id)) {
+                if (prev.some((item) => item.product_id === product.id && item.variant_id === variantId)) {
                     return prev;
                 }
-                return [...prev, product];
+                return [...prev, cartLine(product, variantId)];
             });
         } catch (err) {
             console.error("Failed to add cart:", err);
         }
     };
 
-    const removeFromCart = async (productId) => {
+    const removeFromCart = async (productId, variantId) => {
         try {
-            await api.delete(`/cart/remove`, { data: { product_id: productId } });
-            setCartItems((prev) => prev.filter((item) => item.product_id !== productId));
+            await api.delete(`/cart/remove`, { data: { product_id: productId, variant_id: variantId } });
+            setCartItems((prev) => prev.filter((item) => !(item.product_id === productId && item.variant_id === variantId)));
         } catch (err) {
             console.error("Failed remove from cart:", err);
         }
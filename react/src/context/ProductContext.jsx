@@ -15,9 +15,9 @@ export const ProductProvider = ({ children }) => {
             setLoading(true);
 
             try {
-                const { data } = await api.get(`/products/get`)
+                const { data } = await api.get(`/products/get?limit=100`)
                 console.log("Fetched products:", data);
-                setProducts(data);
+                setProducts(data.products || []);
             } catch (err) {
                 console.error("Error fetching products:", err);
                 setError(err.message);
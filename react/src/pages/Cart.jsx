@@ -3,7 +3,7 @@ import Header from "../components/Header";
 import Loading from "../components/Loading";
 import { useNavigate } from "react-router-dom";
 
-// i am doing localhost for uploads rn 
+// only for images uploaded before they were stored as urls
 const IMGURL = "http://localhost:8082/uploads/"
 const Cart = () => {
     const { cartItems, loading, error, removeFromCart, cart } = useCart();
@@ -23,16 +23,16 @@ const Cart = () => {
 
                     <div className="flex-1 space-y-6 bg-white p-6 md:p-8 rounded-xl shadow-lg">
                         {cartItems.map((item) => {
-                            const imgSrc = item.image?.startsWith("uploads/") ? item.image.slice(8) : item.image;
+                            const imgSrc = item.image?.startsWith("http") ? item.image : `${IMGURL}${item.image?.replace(/^uploads\//, "")}`;
                             const itemPrice = (item.price_cents || 0) / 100;
                             return (
-                                <div key={item.product_id}
+                                <div key={`${item.product_id}-${item.variant_id ?? ""}`}
                                     className="flex items-start justify-between border-b last:border-b-0 pb-6 pt-2">
                                     <div className="flex items-start gap-4">
-                                        <img src={`${IMGURL}${imgSrc}`} alt={item.name} className="w-24 h-24 object-cover rounded-lg shadow-md" />
+                                        <img src={imgSrc} alt={item.name} className="w-24 h-24 object-cover rounded-lg shadow-md" />
                                         <div>
-                                            <h2 className="font-semibold text-xl text-gray-800">{item.name}</h2>
-                                            <button onClick={() => removeFromCart(item.product_id)} className="mt-3 text-sm text-red-600 font-medium hover:text-red-800 transition duration-150"> Remove </button>
+                                            <h2 className="font-semibold text-xl text-gray-800">{item.name}{item.options && ` (${Object.values(item.options).join(" / ")})`}</h2>
+                                            <button onClick={() => removeFromCart(item.product_id, item.variant_id)} className="mt-3 text-sm text-red-600 font-medium hover:text-red-800 transition duration-150"> Remove </button>
                                         </div>
                                     </div>
 
@@ -81,7 +81,7 @@ const CartAside = ({ cartItems, cart }) => {
                 {cartItems.map((item) => {
                     const itemPrice = (item.price_cents || 0) / 100;
                     return (
-                        <div key={item.product_id} className="flex justify-between text-base text-gray-600">
+                        <div key={`${item.product_id}-${item.variant_id ?? ""}`} className="flex justify-between text-base text-gray-600">
                             <span> {item.name} <span className="text-sm font-bold"></span>
                             </span>
                             <span>${(itemPrice * (item.quantity || 1)).toFixed(2)}</span>
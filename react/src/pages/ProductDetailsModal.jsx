@@ -1,9 +1,42 @@
+import { useEffect, useState } from "react";
 import { useCart } from "../context/CartContext";
+import api from "../api";
+
+const RecommendationRow = ({ title, products, onAdd }) => {
+    if (!products?.length) return null;
+    return (
+        <div>
+            <h3 className="text-gray-500 font-medium text-sm uppercase mb-2">{title}</h3>
+            <div className="flex gap-3 overflow-x-auto">
+                {products.map((p) => (
+                    <div key={p.id} className="w-28 shrink-0 text-sm">
+                        <img src={p.images?.thumbnail?.jpeg ?? p.image} alt={p.name} className="w-28 h-28 object-cover rounded-md" />
+                        <p className="truncate mt-1">{p.name}</p>
+                        <button onClick={() => onAdd(p)} className="text-blue-600 hover:underline">${p.price} · Add</button>
+                    </div>
+                ))}
+            </div>
+        </div>
+    );
+};
 
 const ProductDetailsModal = ({ product, onClose }) => {
     const { addToCart } = useCart();
+    const [selected, setSelected] = useState(0);
+    const [recs, setRecs] = useState(null);
+
+    useEffect(() => {
+        if (!product) return;
+        setRecs(null);
+        api.get(`/products/${product.id}/recommendations`)
+            .then((res) => setRecs(res.data))
+            .catch((err) => console.error("err fetching recommendations", err));
+    }, [product?.id]);
 
     if (!product) return null;
+    // older products have no gallery, just the one image
+    const gallery = product.gallery?.length ? product.gallery : [{ url: product.image, images: product.images, alt: product.name }];
+    const current = gallery[Math.min(selected, gallery.length - 1)];
 
     return (
         <div
@@ -31,8 +64,16 @@ const ProductDetailsModal = ({ product, onClose }) => {
                             <hr class="m-2 border-dashed mt-7 mb-7"></hr>
                             <div>
                                 <h3 className="text-gray-500 font-medium text-sm uppercase mb-1">Price</h3>
-                                <p className="text-3xl font-bold text-black">${product.price}</p>
+                                <p className="text-3xl font-bold text-black">
+                                    ${product.price}
+                                    {product.compare_at_price > 0 && <s className="text-lg text-gray-500 ml-3">${product.compare_at_price}</s>}
+                                </p>
+                                {product.sale_ends_at && (
+                                    <p className="text-sm text-red-600 mt-1">Sale ends {new Date(product.sale_ends_at).toLocaleString()}</p>
+                                )}
                             </div>
+                            <RecommendationRow title="Frequently bought together" products={recs?.bought_together} onAdd={addToCart} />
+                            <RecommendationRow title="You may also like" products={recs?.related} onAdd={addToCart} />
                         </div>
 
                         <button
@@ -41,8 +82,19 @@ const ProductDetailsModal = ({ product, onClose }) => {
                                 onClose();
                             }} className="w-full bg-blue-600 text-white font-semibold py-2 rounded-lg shadow-lg hover:bg-blue-700 transition duration-300 transform hover:scale-[1.01] mt-6" >Add to Cart</button>
                     </div>
-                       <div className="md:w-1/2 p-6 flex justify-center items-center bg-gray-50">
-                        <img src={product.image} alt={product.name} className="w-full h-full max-h-[calc(85vh-3rem)] object-contain rounded-xl" />
+                       <div className="md:w-1/2 p-6 flex flex-col justify-center items-center bg-gray-50">
+                        <picture>
+                            {current.images?.detail && <source srcSet={current.images.detail.webp} type="image/webp" />}
+                            <img src={current.images?.detail?.jpeg ?? current.url} alt={current.alt} className="w-full h-full max-h-[calc(85vh-9rem)] object-contain rounded-xl" />
+                        </picture>
+                        {gallery.length > 1 && (
+                            <div className="flex gap-2 mt-4 overflow-x-auto">
+                                {gallery.map((img, i) => (
+                                    <img key={img.id} src={img.images?.thumbnail?.jpeg ?? img.url} alt={img.alt} onClick={() => setSelected(i)}
+                                        className={`w-16 h-16 object-cover rounded-md cursor-pointer ${i === selected ? "ring-2 ring-blue-500" : "opacity-70"}`} />
+                                ))}
+                            </div>
+                        )}
                     </div>
                 </div>
             </div>
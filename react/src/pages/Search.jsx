@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import Header from '../components/Header';
 import ProductCard from '../components/ProductCard';
 import api from '../api';
@@ -9,15 +9,36 @@ const Search = () => {
     const [results, setResults] = useState([]);
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState(null);
+    const [suggestions, setSuggestions] = useState([]);
+    const [searched, setSearched] = useState('');
 
-    const handleSearch = async () => {
-        if (!query) return;
+    // ask for completions once typing pauses
+    useEffect(() => {
+        if (!query.trim() || query === searched) {
+            setSuggestions([]);
+            return;
+        }
+        const timer = setTimeout(async () => {
+            try {
+                const res = await api.get(`/products/suggest?q=${encodeURIComponent(query)}&limit=8`);
+                setSuggestions(res.data.suggestions || []);
+            } catch (err) {
+                console.error(err);
+            }
+        }, 150);
+        return () => clearTimeout(timer);
+    }, [query, searched]);
+
+    const handleSearch = async (q = query) => {
+        if (!q) return;
+        setSearched(q);
+        setSuggestions([]);
         setLoading(true);
         setError(null);
 
         try {
-            const res = await api.get(`/products/search?q=${encodeURIComponent(query)}`);
-            setResults(res.data || []);
+            const res = await api.get(`/products/search?q=${encodeURIComponent(q)}`);
+            setResults(res.data.products || []);
         } catch (err) {
             console.error(err);
             setError(err.response?.data?.message || err.message || 'couldnt fetch products');
@@ -29,6 +50,12 @@ const Search = () => {
 
     const handleKeyPress = (e) => {
         if (e.key === 'Enter') handleSearch();
+        if (e.key === 'Escape') setSuggestions([]);
+    };
+
+    const pickSuggestion = (s) => {
+        setQuery(s.text);
+        handleSearch(s.text);
     };
 
     return (
@@ -38,7 +65,7 @@ const Search = () => {
             <div className="min-h-screen flex justify-center p-6 ">
                 <div className="w-full max-w-7xl text-center backdrop-blur-md bg-white/30 rounded-2xl p-6">
                     <h1 className="text-3xl font-bold mb-6">Search Products</h1>
-                    <div className="flex gap-3 mb-8">
+                    <div className="relative flex gap-3 mb-8">
                         <input
                             type="text"
                             value={query}
@@ -46,7 +73,18 @@ const Search = () => {
                             onKeyDown={handleKeyPress}
                             placeholder="Enter product here"
                             className="flex-1 p-3 border-2 border-transparent bg-white/20 rounded-xl shadow-lg focus:ring-blue-500"/>
-                        <button onClick={handleSearch} className="px-6 py-3 bg-blue-500 text-white rounded-xl shadow hover:bg-blue-600 transition duration-300 hover:scale-[1.07] ">Search</button>
+                        {suggestions.length > 0 && (
+                            <ul className="absolute left-0 top-full mt-1 w-full max-w-xl z-10 bg-white rounded-xl shadow-lg text-left overflow-hidden">
+                                {suggestions.map((s) => (
+                                    <li key={s.kind + s.text} onMouseDown={() => pickSuggestion(s)}
+                                        className="px-4 py-2 cursor-pointer hover:bg-blue-50 flex justify-between">
+                                        <span>{s.text}</span>
+                                        {s.kind === 'category' && <span className="text-xs text-gray-500">category</span>}
+                                    </li>
+                                ))}
+                            </ul>
+                        )}
+                        <button onClick={() => handleSearch()} className="px-6 py-3 bg-blue-500 text-white rounded-xl shadow hover:bg-blue-600 transition duration-300 hover:scale-[1.07] ">Search</button>
                     </div>
 
                     {loading && <Loading/>}